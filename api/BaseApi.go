@@ -29,12 +29,26 @@ var client = http.Client{
 	Timeout: 30 * time.Second,
 }
 
-func request(r http.Request, errMessage string) ([]byte, error) {
-	response, err := client.Do(&r)
+// SetHTTPTransport 设置访问蓝盾后台使用的 http.RoundTripper，传入 nil 时恢复默认，返回原来的设置
+// 需在发起请求前设置，可用于测试中录制与回放请求
+func SetHTTPTransport(transport http.RoundTripper) http.RoundTripper {
+	old := client.Transport
+	client.Transport = transport
+	return old
+}
+
+// request 发送请求，运行时设置了 transport 时使用该运行时自己的 transport
+func (r *Runtime) request(req http.Request, errMessage string) ([]byte, error) {
+	c := &client
+	if r.transport != nil {
+		c = &http.Client{Timeout: client.Timeout, Transport: r.transport}
+	}
+	response, err := c.Do(&req)
 	if err != nil {
 		log.Error("do http request failed: " + err.Error())
 		return nil, errors.New(errMessage)
 	}
+	defer response.Body.Close()
 
 	if !(response.StatusCode >= 200 && response.StatusCode < 300) {
 		log.Error("http request failed, status: " + strconv.Itoa(response.StatusCode))
@@ -50,12 +64,12 @@ func request(r http.Request, errMessage string) ([]byte, error) {
 	return respStr, nil
 }
 
-func buildGet(build BuildRequest) (*http.Request, error) {
+func (r *Runtime) buildGet(build BuildRequest) (*http.Request, error) {
 	if build.path == "" {
 		return nil, errors.New("can not generate request without path")
 	}
 
-	url := buildUrl(build.path)
+	url := r.buildUrl(build.path)
 	req, err := http.NewRequest("GET", url, nil)
 	if err != nil {
 		return nil, err
@@ -70,12 +84,12 @@ func buildGet(build BuildRequest) (*http.Request, error) {
 	return req, nil
 }
 
-func buildPost(build BuildRequest) (*http.Request, error) {
+func (r *Runtime) buildPost(build BuildRequest) (*http.Request, error) {
 	if build.path == "" {
 		return nil, errors.New("can not generate request without path")
 	}
 
-	url := buildUrl(build.path)
+	url := r.buildUrl(build.path)
 	req, err := http.NewRequest("POST", url, build.requestBody)
 	if err != nil {
 		return nil, err
@@ -90,12 +104,12 @@ func buildPost(build BuildRequest) (*http.Request, error) {
 	return req, nil
 }
 
-func buildPut(build BuildRequest) (*http.Request, error) {
+func (r *Runtime) buildPut(build BuildRequest) (*http.Request, error) {
 	if build.path == "" {
 		return nil, errors.New("can not generate request without path")
 	}
 
-	url := buildUrl(build.path)
+	url := r.buildUrl(build.path)
 	req, err := http.NewRequest("PUT", url, build.requestBody)
 	if err != nil {
 		return nil, err
@@ -110,12 +124,12 @@ func buildPut(build BuildRequest) (*http.Request, error) {
 	return req, nil
 }
 
-func buildDelete(build BuildRequest) (*http.Request, error) {
+func (r *Runtime) buildDelete(build BuildRequest) (*http.Request, error) {
 	if build.path == "" {
 		return nil, errors.New("can not generate request without path")
 	}
 
-	url := buildUrl(build.path)
+	url := r.buildUrl(build.path)
 	req, err := http.NewRequest("DELETE", url, build.requestBody)
 	if err != nil {
 		return nil, err
@@ -130,22 +144,26 @@ func buildDelete(build BuildRequest) (*http.Request, error) {
 	return req, nil
 }
 
-func getAllHeaders() map[string]string {
+func (r *Runtime) getAllHeaders() map[string]string {
 	var headers = make(map[string]string)
-	headers[AuthHeaderDevopsBuildType] = gSdkEvn.BuildType
-	headers[AuthHeaderProjectId] = gSdkEvn.ProjectId
-	headers[AuthHeaderDevopsProjectId] = gSdkEvn.ProjectId
-	headers[AuthHeaderDevopsBuildId] = gSdkEvn.BuildId
-	headers[AuthHeaderDevopsAgentSecretKey] = gSdkEvn.SecretKey
-	headers[AuthHeaderDevopsAgentId] = gSdkEvn.AgentId
-	headers[AuthHeaderDevopsVmSeqId] = gSdkEvn.VmSeqId
-	headers[AuthHeaderBuildId] = gSdkEvn.BuildId
-	headers[AuthHeaderDevopsCiTaskId] = gSdkEvn.TaskId
+	headers[AuthHeaderDevopsBuildType] = r.SdkEnv.BuildType
+	headers[AuthHeaderProjectId] = r.SdkEnv.ProjectId
+	headers[AuthHeaderDevopsProjectId] = r.SdkEnv.ProjectId
+	headers[AuthHeaderDevopsBuildId] = r.SdkEnv.BuildId
+	headers[AuthHeaderDevopsAgentSecretKey] = r.SdkEnv.SecretKey
+	headers[AuthHeaderDevopsAgentId] = r.SdkEnv.AgentId
+	headers[AuthHeaderDevopsVmSeqId] = r.SdkEnv.VmSeqId
+	headers[AuthHeaderBuildId] = r.SdkEnv.BuildId
+	headers[AuthHeaderDevopsCiTaskId] = r.SdkEnv.TaskId
 	return headers
 }
 
-func buildUrl(path string) string {
-	var gateway = strings.TrimSuffix(gSdkEvn.Gateway, "/")
+func (r *Runtime) buildUrl(path string) string {
+	// 已经是完整地址时直接使用
+	if strings.HasPrefix(path, "http://") || strings.HasPrefix(path, "https://") {
+		return path
+	}
+	var gateway = strings.TrimSuffix(r.SdkEnv.Gateway, "/")
 	if strings.HasPrefix(gateway, "http") {
 		return gateway + "/" + strings.TrimPrefix(strings.TrimSpace(path), "/")
 	} else {
@@ -13,17 +13,17 @@ type Certificate struct {
 }
 
 // GetCertificate 获取指定ID的凭证
-func GetCertificate(certificateId string) map[string]string {
+func (r *Runtime) GetCertificate(certificateId string) map[string]string {
 	log.Info("Begin to get certificate")
-	url := buildUrl("/ticket/api/build/credentials/" + certificateId + "/detail")
-	var build = BuildRequest{path: url, requestBody: nil, headers: getAllHeaders()}
-	req, err := buildGet(build)
+	url := r.buildUrl("/ticket/api/build/credentials/" + certificateId + "/detail")
+	var build = BuildRequest{path: url, requestBody: nil, headers: r.getAllHeaders()}
+	req, err := r.buildGet(build)
 	if err != nil {
 		log.Error("build request failed: " + err.Error())
 		return nil
 	}
 
-	respByte, err := request(*req, "failed to get certificate")
+	respByte, err := r.request(*req, "failed to get certificate")
 	if err != nil {
 		log.Error("get certificate failed: " + err.Error())
 		return nil
@@ -37,3 +37,8 @@ func GetCertificate(certificateId string) map[string]string {
 
 	return certificate.Data
 }
+
+// GetCertificate 获取指定ID的凭证
+func GetCertificate(certificateId string) map[string]string {
+	return DefaultRuntime().GetCertificate(certificateId)
+}
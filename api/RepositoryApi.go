@@ -2,6 +2,7 @@ package api
 
 import (
 	"encoding/json"
+	"errors"
 	"io"
 
 	"github.com/ci-plugins/golang-plugin-sdk/log"
@@ -45,18 +46,18 @@ const (
 )
 
 // GetCommit 获取当前流水线构建下的“代码变更记录”
-func GetCommit() (*CommitResult, error) {
-	url := buildUrl("/repository/api/build/commit/getCommitsByBuildId")
-	headers := getAllHeaders()
+func (r *Runtime) GetCommit() (*CommitResult, error) {
+	url := r.buildUrl("/repository/api/build/commit/getCommitsByBuildId")
+	headers := r.getAllHeaders()
 	headers["Content-type"] = "application/json"
 	build := BuildRequest{path: url, headers: headers, requestBody: nil}
-	req, err := buildGet(build)
+	req, err := r.buildGet(build)
 	if err != nil {
 		log.Error("fail to generate request: ", err)
 		return nil, err
 	}
 
-	respByte, err := request(*req, "fail to get commit history")
+	respByte, err := r.request(*req, "fail to get commit history")
 	if err != nil {
 		log.Error(err)
 		return nil, err
@@ -73,44 +74,67 @@ func GetCommit() (*CommitResult, error) {
 	return result, nil
 }
 
+// GetCommit 获取当前流水线构建下的“代码变更记录”
+func GetCommit() (*CommitResult, error) {
+	return DefaultRuntime().GetCommit()
+}
+
 // GetRepoInfo 获取指定GIT仓库的信息（包括代码库地址），这里的返回值字段因代码库的类型（@type字段）决定，所以返回值设为map
-func GetRepoInfo(repoType repositoryType, repoId string) (map[string]interface{}, error) {
+func (r *Runtime) GetRepoInfo(repoType repositoryType, repoId string) (map[string]interface{}, error) {
 	address := "/repository/api/build/repositories/?repositoryId=" + repoId + "&repositoryType=" + string(repoType)
-	result, err := sendGetHttp(address, nil)
+	result, err := r.sendGetHttp(address, nil)
 	if err != nil {
 		log.Error("get git repo info error: " + err.Error())
 		return nil, err
 	}
 
-	data := result.Data.(map[string]interface{})
+	data, ok := result.Data.(map[string]interface{})
+	if !ok && result.Data != nil {
+		log.Error("unexpected response data: ", result.Data)
+		return nil, errors.New("unexpected response data")
+	}
 	return data, nil
 }
 
+// GetRepoInfo 获取指定GIT仓库的信息（包括代码库地址），这里的返回值字段因代码库的类型（@type字段）决定，所以返回值设为map
+func GetRepoInfo(repoType repositoryType, repoId string) (map[string]interface{}, error) {
+	return DefaultRuntime().GetRepoInfo(repoType, repoId)
+}
+
 // GetGitOauth 获取 git  oauth 信息
-func GetGitOauth(userID string) (map[string]interface{}, error) {
+func (r *Runtime) GetGitOauth(userID string) (map[string]interface{}, error) {
 	address := "/repository/api/build/oauth/git/" + userID
-	result, err := sendGetHttp(address, nil)
+	result, err := r.sendGetHttp(address, nil)
 	if err != nil {
 		log.Error("get git oauth error: " + err.Error())
 		return nil, err
 	}
 
-	data := result.Data.(map[string]interface{})
+	data, ok := result.Data.(map[string]interface{})
+	if !ok && result.Data != nil {
+		log.Error("unexpected response data: ", result.Data)
+		return nil, errors.New("unexpected response data")
+	}
 	return data, nil
 }
 
-func sendGetHttp(address string, requestBody io.Reader) (*Result, error) {
-	url := buildUrl(address)
-	headers := getAllHeaders()
+// GetGitOauth 获取 git  oauth 信息
+func GetGitOauth(userID string) (map[string]interface{}, error) {
+	return DefaultRuntime().GetGitOauth(userID)
+}
+
+func (r *Runtime) sendGetHttp(address string, requestBody io.Reader) (*Result, error) {
+	url := r.buildUrl(address)
+	headers := r.getAllHeaders()
 	headers["Content-type"] = "application/json"
 	build := BuildRequest{path: url, headers: headers, requestBody: requestBody}
-	req, err := buildGet(build)
+	req, err := r.buildGet(build)
 	if err != nil {
 		log.Error("fail to generate request: ", err)
 		return nil, err
 	}
 
-	respByte, err := request(*req, "fail to get request info")
+	respByte, err := r.request(*req, "fail to get request info")
 	if err != nil {
 		log.Error("request fail: ", err)
 		return nil, err
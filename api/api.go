@@ -27,77 +27,40 @@
 package api
 
 import (
+	"context"
 	"encoding/json"
 	"errors"
-	"flag"
 	"fmt"
 	"io/ioutil"
 	"os"
-	"strings"
 
 	"github.com/ci-plugins/golang-plugin-sdk/log"
 )
 
-var gSdkEvn *SdkEnv
-var gAtomBaseParam *AtomBaseParam
-var gAllAtomParam map[string]interface{}
-var gAtomOutput *AtomOutput
-
-var gDataDir string
-var gInputFile string
-var gOutputFile string
-
 // StringResult 蓝盾后台返回结果
 type StringResult struct {
 	Status int         `json:"status"`
 	Data   interface{} `json:"data"`
 }
 
-func init() {
-	gAtomOutput = NewAtomOutput()
-	gDataDir = getDataDir()
-	gInputFile = getInputFile()
-	gOutputFile = getOutputFile()
-	initSdkEnv()
-	initAtomParam()
-}
-
-func initAtomParam() {
-	err := LoadInputParam(&gAllAtomParam)
-	if err != nil {
-		log.Error("init atom base param failed: ", err.Error())
-		FinishBuildWithError(StatusError, "init atom base param failed", 2189503, PluginError)
-	}
-
-	gAtomBaseParam = new(AtomBaseParam)
-	err = LoadInputParam(gAtomBaseParam)
-	postActionParam := flag.String("postAction", "noPostAction", "后置动作")
-	flag.Parse()
-	gAtomBaseParam.PostActionParam = *postActionParam
-	if err != nil {
-		log.Error("init atom base param failed: ", err.Error())
-		FinishBuildWithError(StatusError, "init atom base param failed", 2189503, PluginError)
-	}
+// GetInputParam 获取输入参数，非字符串的值会转换为字符串，对象与数组转换为 JSON
+// @name	参数名称
+func (r *Runtime) GetInputParam(name string) string {
+	return stringValue(r.AllAtomParam[name])
 }
 
-// GetInputParam 获取输入参数
+// GetInputParam 获取输入参数，非字符串的值会转换为字符串，对象与数组转换为 JSON
 // @name	参数名称
 func GetInputParam(name string) string {
-	value := gAllAtomParam[name]
-	if value == nil {
-		return ""
-	}
-	strValue, ok := value.(string)
-	if !ok {
-		return ""
-	}
-	return strValue
+	return DefaultRuntime().GetInputParam(name)
 }
 
 // LoadInputParam 加载输入参数
-func LoadInputParam(v interface{}) error {
-	file := gDataDir + "/" + gInputFile
-	data, err := ioutil.ReadFile(file)
+func (r *Runtime) LoadInputParam(v interface{}) error {
+	data, err := ioutil.ReadFile(r.InputFilePath())
+	if os.IsNotExist(err) && r.local {
+		return r.loadLocalInput(v)
+	}
 	if err != nil {
 		log.Error("load input param failed:", err.Error())
 		return errors.New("load input param failed")
@@ -110,117 +73,156 @@ func LoadInputParam(v interface{}) error {
 	return nil
 }
 
-func initSdkEnv() {
-	filePath := gDataDir + "/.sdk.json"
-	data, err := ioutil.ReadFile(filePath)
-	if err != nil {
-		log.Error("read .sdk.json failed: ", err.Error())
-		FinishBuildWithError(StatusError, "read .sdk.json failed", 2189503, PluginError)
-	}
-
-	gSdkEvn = new(SdkEnv)
-	err = json.Unmarshal(data, gSdkEvn)
-	if err != nil {
-		log.Error("parse .sdk.json failed: ", err.Error())
-		FinishBuildWithError(StatusError, "read .sdk.json failed", 2189503, PluginError)
-	}
-
-	// os.Remove(filePath)
+// LoadInputParam 加载输入参数
+func LoadInputParam(v interface{}) error {
+	return DefaultRuntime().LoadInputParam(v)
 }
 
-func getDataDir() string {
-	dir := strings.TrimSpace(os.Getenv(DataDirEnv))
-	if len(dir) == 0 {
-		dir, _ = os.Getwd()
-	}
-	return dir
+// GetOutputData 获取输出参数，返回已保存的值，可直接修改
+// 与 checkpoint 或其他协程并发修改时请使用 AddArtifactOutput 等方法，或修改 GetArtifactOutput 等返回的副本后重新添加
+func (r *Runtime) GetOutputData(key string) interface{} {
+	key = r.outputKey(key)
+	r.outputLock.RLock()
+	defer r.outputLock.RUnlock()
+	return r.AtomOutput.Data[key]
 }
 
-func getInputFile() string {
-	file := strings.TrimSpace(os.Getenv(InputFileEnv))
-	if len(file) == 0 {
-		file = "input.json"
-	}
-	return file
+// GetOutputData 获取输出参数，返回已保存的值，可直接修改
+func GetOutputData(key string) interface{} {
+	return DefaultRuntime().GetOutputData(key)
 }
 
-func getOutputFile() string {
-	file := strings.TrimSpace(os.Getenv(OutputFileEnv))
-	if len(file) == 0 {
-		file = "output.json"
-	}
-	return file
+// AddOutputData 添加输出参数，开启 SetNamespaceOutputs 时 key 会自动加上命名空间前缀
+func (r *Runtime) AddOutputData(key string, data interface{}) {
+	key = r.outputKey(key)
+	r.updateOutput(func(output *AtomOutput) {
+		output.Data[key] = data
+	})
 }
 
-// GetOutputData 获取输出参数
-func GetOutputData(key string) interface{} {
-	return gAtomOutput.Data[key]
+// AddOutputData 添加输出参数，开启 SetNamespaceOutputs 时 key 会自动加上命名空间前缀
+func AddOutputData(key string, data interface{}) {
+	DefaultRuntime().AddOutputData(key, data)
 }
 
-// AddOutputData 添加输出参数
-func AddOutputData(key string, data interface{}) {
-	gAtomOutput.Data[key] = data
+// RemoveOutputData 删除输出参数
+func (r *Runtime) RemoveOutputData(key string) {
+	key = r.outputKey(key)
+	r.updateOutput(func(output *AtomOutput) {
+		delete(output.Data, key)
+	})
 }
 
 // RemoveOutputData 删除输出参数
 func RemoveOutputData(key string) {
-	delete(gAtomOutput.Data, key)
+	DefaultRuntime().RemoveOutputData(key)
+}
+
+// GetQualityData 获取质量红线信息，返回已保存的值，与 GetOutputData 相同可直接修改
+func (r *Runtime) GetQualityData(qualityKey string) interface{} {
+	r.outputLock.RLock()
+	defer r.outputLock.RUnlock()
+	return r.AtomOutput.QualityData[qualityKey]
 }
 
 // GetQualityData 获取质量红线信息
 func GetQualityData(qualityKey string) interface{} {
-	return gAtomOutput.QualityData[qualityKey]
+	return DefaultRuntime().GetQualityData(qualityKey)
+}
+
+// AddQualityData 添加质量红线信息
+func (r *Runtime) AddQualityData(qualityKey string, qualitydata *Qualitydata) {
+	r.updateOutput(func(output *AtomOutput) {
+		output.Type = "quality"
+		output.QualityData[qualityKey] = qualitydata
+	})
 }
 
 // AddQualityData 添加质量红线信息
 func AddQualityData(qualityKey string, qualitydata *Qualitydata) {
-	gAtomOutput.Type = "quality"
-	gAtomOutput.QualityData[qualityKey] = qualitydata
+	DefaultRuntime().AddQualityData(qualityKey, qualitydata)
+}
+
+// RemoveQualityData 删除质量红线信息
+func (r *Runtime) RemoveQualityData(qualityKey string) {
+	r.updateOutput(func(output *AtomOutput) {
+		delete(output.QualityData, qualityKey)
+	})
 }
 
 // RemoveQualityData 删除质量红线信息
 func RemoveQualityData(qualityKey string) {
-	delete(gAtomOutput.QualityData, qualityKey)
+	DefaultRuntime().RemoveQualityData(qualityKey)
+}
+
+// SetPlatformCode 设置插件对接平台代码
+func (r *Runtime) SetPlatformCode(platformCode string) {
+	r.updateOutput(func(output *AtomOutput) {
+		output.PlatformCode = platformCode
+	})
 }
 
 // SetPlatformCode 设置插件对接平台代码
 func SetPlatformCode(platformCode string) {
-	gAtomOutput.PlatformCode = platformCode
+	DefaultRuntime().SetPlatformCode(platformCode)
+}
+
+// SetPlatformErrorCode 设置插件对接平台错误码
+func (r *Runtime) SetPlatformErrorCode(platformErrorCode int) {
+	r.updateOutput(func(output *AtomOutput) {
+		output.PlatformErrorCode = platformErrorCode
+	})
 }
 
 // SetPlatformErrorCode 设置插件对接平台错误码
 func SetPlatformErrorCode(platformErrorCode int) {
-	gAtomOutput.PlatformErrorCode = platformErrorCode
+	DefaultRuntime().SetPlatformErrorCode(platformErrorCode)
 }
 
 // WriteOutput 将输出写到文件
-func WriteOutput() error {
-	data, _ := json.Marshal(gAtomOutput)
-
-	file := gDataDir + "/" + gOutputFile
-	err := ioutil.WriteFile(file, data, 0644)
+// 设置了输出校验时先按 task.json 校验输出，严格模式下校验失败会将执行状态改为失败并返回错误
+func (r *Runtime) WriteOutput() error {
+	checkErr := r.checkOutput()
+	r.writeLock.Lock()
+	defer r.writeLock.Unlock()
+	data, _ := json.Marshal(r.OutputSnapshot())
+
+	err := writeFileAtomic(r.OutputFilePath(), data)
 	if err != nil {
 		log.Error("write output failed: ", err.Error())
 		return errors.New("write output failed")
 	}
-	return nil
+	return checkErr
+}
+
+// WriteOutput 将输出写到文件
+func WriteOutput() error {
+	return DefaultRuntime().WriteOutput()
+}
+
+// FinishBuild 结束构建
+func (r *Runtime) FinishBuild(status Status, msg string) {
+	r.finish(func(output *AtomOutput) {
+		output.Message = msg
+		output.Status = status
+	})
 }
 
 // FinishBuild 结束构建
 func FinishBuild(status Status, msg string) {
-	gAtomOutput.Message = msg
-	gAtomOutput.Status = status
-	WriteOutput()
-	switch status {
-	case StatusSuccess:
-		os.Exit(0)
-	case StatusFailure:
-		os.Exit(1)
-	case StatusError:
-		os.Exit(2)
-	default:
-		os.Exit(0)
-	}
+	DefaultRuntime().FinishBuild(status, msg)
+}
+
+// FinishBuildWithErrorCode 结束构建
+// @status		任务状态
+// @msg			消息
+// @errorCode	错误码
+func (r *Runtime) FinishBuildWithErrorCode(status Status, msg string, errorCode int) {
+	r.finish(func(output *AtomOutput) {
+		output.Message = msg
+		output.Status = status
+		output.ErrorCode = errorCode
+	})
 }
 
 // FinishBuildWithErrorCode 结束构建
@@ -228,20 +230,21 @@ func FinishBuild(status Status, msg string) {
 // @msg			消息
 // @errorCode	错误码
 func FinishBuildWithErrorCode(status Status, msg string, errorCode int) {
-	gAtomOutput.Message = msg
-	gAtomOutput.Status = status
-	gAtomOutput.ErrorCode = errorCode
-	WriteOutput()
-	switch status {
-	case StatusSuccess:
-		os.Exit(0)
-	case StatusFailure:
-		os.Exit(1)
-	case StatusError:
-		os.Exit(2)
-	default:
-		os.Exit(0)
-	}
+	DefaultRuntime().FinishBuildWithErrorCode(status, msg, errorCode)
+}
+
+// FinishBuildWithError 结束构建
+// @status		任务状态
+// @msg			消息
+// @errorCode	错误码
+// @errorType	错误类型
+func (r *Runtime) FinishBuildWithError(status Status, msg string, errorCode int, errorType ErrorType) {
+	r.finish(func(output *AtomOutput) {
+		output.Message = msg
+		output.Status = status
+		output.ErrorCode = errorCode
+		output.ErrorType = errorType
+	})
 }
 
 // FinishBuildWithError 结束构建
@@ -250,118 +253,234 @@ func FinishBuildWithErrorCode(status Status, msg string, errorCode int) {
 // @errorCode	错误码
 // @errorType	错误类型
 func FinishBuildWithError(status Status, msg string, errorCode int, errorType ErrorType) {
-	gAtomOutput.Message = msg
-	gAtomOutput.Status = status
-	gAtomOutput.ErrorCode = errorCode
-	gAtomOutput.ErrorType = errorType
-	WriteOutput()
-	switch status {
-	case StatusSuccess:
-		os.Exit(0)
-	case StatusFailure:
-		os.Exit(1)
-	case StatusError:
-		os.Exit(2)
-	default:
-		os.Exit(0)
+	DefaultRuntime().FinishBuildWithError(status, msg, errorCode, errorType)
+}
+
+// finish 执行清理逻辑，更新插件输出并写入输出文件后退出，同一运行时只会生效一次
+func (r *Runtime) finish(update func(output *AtomOutput)) {
+	r.finishLock.Lock()
+	if r.finished {
+		r.finishLock.Unlock()
+		log.Warn("build already finished, ignore")
+		return
 	}
+	r.finished = true
+	r.finishLock.Unlock()
+	r.stopCheckpoint()
+
+	runCleanup(context.Background())
+
+	r.updateOutput(update)
+	r.WriteOutput()
+	if r.local {
+		r.printLocalOutput()
+	}
+
+	r.exit()
+}
+
+// updateOutput 持有输出锁修改插件输出，开启检查点时随后写入检查点
+func (r *Runtime) updateOutput(update func(output *AtomOutput)) {
+	r.outputLock.Lock()
+	update(r.AtomOutput)
+	r.outputLock.Unlock()
+	r.outputChanged()
+}
+
+// OutputSnapshot 获取插件输出的快照，快照与运行时的输出互不影响，可在设置输出的同时安全读取
+func (r *Runtime) OutputSnapshot() *AtomOutput {
+	r.outputLock.RLock()
+	defer r.outputLock.RUnlock()
+	return r.AtomOutput.clone()
+}
+
+// OutputSnapshot 获取插件输出的快照，快照与运行时的输出互不影响，可在设置输出的同时安全读取
+func OutputSnapshot() *AtomOutput {
+	return DefaultRuntime().OutputSnapshot()
+}
+
+// SetAtomOutputType 获取插件输出类型
+func (r *Runtime) SetAtomOutputType(atomOutputType string) {
+	r.updateOutput(func(output *AtomOutput) {
+		output.Type = atomOutputType
+	})
 }
 
 // SetAtomOutputType 获取插件输出类型
 func SetAtomOutputType(atomOutputType string) {
-	gAtomOutput.Type = atomOutputType
+	DefaultRuntime().SetAtomOutputType(atomOutputType)
+}
+
+// GetProjectName 获取项目名称
+func (r *Runtime) GetProjectName() string {
+	return r.AtomBaseParam.ProjectName
 }
 
 // GetProjectName 获取项目名称
 func GetProjectName() string {
-	return gAtomBaseParam.ProjectName
+	return DefaultRuntime().GetProjectName()
+}
+
+// GetProjectDisplayName 获取项目显示名称
+func (r *Runtime) GetProjectDisplayName() string {
+	return r.AtomBaseParam.ProjectNameCn
 }
 
 // GetProjectDisplayName 获取项目显示名称
 func GetProjectDisplayName() string {
-	return gAtomBaseParam.ProjectNameCn
+	return DefaultRuntime().GetProjectDisplayName()
+}
+
+// GetPipelineId 获取流水线ID
+func (r *Runtime) GetPipelineId() string {
+	return r.AtomBaseParam.PipelineId
 }
 
 // GetPipelineId 获取流水线ID
 func GetPipelineId() string {
-	return gAtomBaseParam.PipelineId
+	return DefaultRuntime().GetPipelineId()
+}
+
+// GetPipelineName 获取流水线名称
+func (r *Runtime) GetPipelineName() string {
+	return r.AtomBaseParam.PipelineName
 }
 
 // GetPipelineName 获取流水线名称
 func GetPipelineName() string {
-	return gAtomBaseParam.PipelineName
+	return DefaultRuntime().GetPipelineName()
+}
+
+// GetPipelineBuildId 获取构建ID
+func (r *Runtime) GetPipelineBuildId() string {
+	return r.AtomBaseParam.PipelineBuildId
 }
 
 // GetPipelineBuildId 获取构建ID
 func GetPipelineBuildId() string {
-	return gAtomBaseParam.PipelineBuildId
+	return DefaultRuntime().GetPipelineBuildId()
+}
+
+// GetPipelineBuildNumber 获取构建号
+func (r *Runtime) GetPipelineBuildNumber() string {
+	return r.AtomBaseParam.PipelineBuildNum
 }
 
 // GetPipelineBuildNumber 获取构建号
 func GetPipelineBuildNumber() string {
-	return gAtomBaseParam.PipelineBuildNum
+	return DefaultRuntime().GetPipelineBuildNumber()
+}
+
+// GetPipelineStartType 获取流水线启动方式
+func (r *Runtime) GetPipelineStartType() string {
+	return r.AtomBaseParam.PipelineStartType
 }
 
 // GetPipelineStartType 获取流水线启动方式
 func GetPipelineStartType() string {
-	return gAtomBaseParam.PipelineStartType
+	return DefaultRuntime().GetPipelineStartType()
+}
+
+// GetPipelineStartUserId 获取流水线启动用户ID
+func (r *Runtime) GetPipelineStartUserId() string {
+	return r.AtomBaseParam.PipelineStartUserId
 }
 
 // GetPipelineStartUserId 获取流水线启动用户ID
 func GetPipelineStartUserId() string {
-	return gAtomBaseParam.PipelineStartUserId
+	return DefaultRuntime().GetPipelineStartUserId()
+}
+
+// GetPipelineStartUserName 获取流水线启动用户名
+func (r *Runtime) GetPipelineStartUserName() string {
+	return r.AtomBaseParam.PipelineStartUserName
 }
 
 // GetPipelineStartUserName 获取流水线启动用户名
 func GetPipelineStartUserName() string {
-	return gAtomBaseParam.PipelineStartUserName
+	return DefaultRuntime().GetPipelineStartUserName()
+}
+
+// GetPipelineStartTimeMills 获取流水线启动时间
+func (r *Runtime) GetPipelineStartTimeMills() string {
+	return r.AtomBaseParam.PipelineStartTimeMills
 }
 
 // GetPipelineStartTimeMills 获取流水线启动时间
 func GetPipelineStartTimeMills() string {
-	return gAtomBaseParam.PipelineStartTimeMills
+	return DefaultRuntime().GetPipelineStartTimeMills()
+}
+
+// GetPipelineVersion 获取流水线版本号
+func (r *Runtime) GetPipelineVersion() string {
+	return r.AtomBaseParam.PipelineVersion
 }
 
 // GetPipelineVersion 获取流水线版本号
 func GetPipelineVersion() string {
-	return gAtomBaseParam.PipelineVersion
+	return DefaultRuntime().GetPipelineVersion()
 }
 
 // GetWorkspace 获取工作目录
-func GetWorkspace() string {
-	if gAtomBaseParam.BkWorkspace == "" {
+func (r *Runtime) GetWorkspace() string {
+	if r.AtomBaseParam.BkWorkspace == "" {
 		return "."
 	}
-	return gAtomBaseParam.BkWorkspace
+	return r.AtomBaseParam.BkWorkspace
+}
+
+// GetWorkspace 获取工作目录
+func GetWorkspace() string {
+	return DefaultRuntime().GetWorkspace()
+}
+
+// GetPipelineCreateUser 获取流水线创建人
+func (r *Runtime) GetPipelineCreateUser() string {
+	return r.AtomBaseParam.PipelineCreateUser
 }
 
 // GetPipelineCreateUser 获取流水线创建人
 func GetPipelineCreateUser() string {
-	return gAtomBaseParam.PipelineCreateUser
+	return DefaultRuntime().GetPipelineCreateUser()
+}
+
+// GetPipelineModifyUser 获取流水线最后修改人
+func (r *Runtime) GetPipelineModifyUser() string {
+	return r.AtomBaseParam.PipelineModifyUser
 }
 
 // GetPipelineModifyUser 获取流水线最后修改人
 func GetPipelineModifyUser() string {
-	return gAtomBaseParam.PipelineModifyUser
+	return DefaultRuntime().GetPipelineModifyUser()
+}
+
+// GetSensitiveConfParam 获取插件敏感参数
+func (r *Runtime) GetSensitiveConfParam(fieldName string) string {
+	return r.AtomBaseParam.BkSensitiveConfInfo[fieldName]
 }
 
 // GetSensitiveConfParam 获取插件敏感参数
 func GetSensitiveConfParam(fieldName string) string {
-	return gAtomBaseParam.BkSensitiveConfInfo[fieldName]
+	return DefaultRuntime().GetSensitiveConfParam(fieldName)
+}
+
+// GetPostActionParam 获取后置执行参数
+func (r *Runtime) GetPostActionParam() string {
+	return r.AtomBaseParam.PostActionParam
 }
 
 // GetPostActionParam 获取后置执行参数
 func GetPostActionParam() string {
-	return gAtomBaseParam.PostActionParam
+	return DefaultRuntime().GetPostActionParam()
 }
 
 // GetBuildVarByKey 获取指定构建下的构建参数
-func GetBuildVarByKey(key string) (string, error) {
+func (r *Runtime) GetBuildVarByKey(key string) (string, error) {
 	if key == "" {
 		return "", errors.New("key is empty")
 	}
 
-	vars, err := GetBuildVar()
+	vars, err := r.GetBuildVar()
 	if err != nil {
 		return "", err
 	}
@@ -374,21 +493,26 @@ func GetBuildVarByKey(key string) (string, error) {
 	return value, nil
 }
 
+// GetBuildVarByKey 获取指定构建下的构建参数
+func GetBuildVarByKey(key string) (string, error) {
+	return DefaultRuntime().GetBuildVarByKey(key)
+}
+
 // GetBuildVar 获取指定构建下的构建参数
-func GetBuildVar() (map[string]interface{}, error) {
-	url := buildUrl("process/api/build/variable/getBuildVariable")
-	headers := getAllHeaders()
-	headers["X-DEVOPS-BUILD-ID"] = gAtomBaseParam.PipelineBuildId
-	headers["X-DEVOPS-PROJECT-ID"] = gAtomBaseParam.ProjectName
-	headers["X-DEVOPS-PIPELINE-ID"] = gAtomBaseParam.PipelineId
+func (r *Runtime) GetBuildVar() (map[string]interface{}, error) {
+	url := r.buildUrl("process/api/build/variable/getBuildVariable")
+	headers := r.getAllHeaders()
+	headers["X-DEVOPS-BUILD-ID"] = r.AtomBaseParam.PipelineBuildId
+	headers["X-DEVOPS-PROJECT-ID"] = r.AtomBaseParam.ProjectName
+	headers["X-DEVOPS-PIPELINE-ID"] = r.AtomBaseParam.PipelineId
 	build := BuildRequest{path: url, headers: headers, requestBody: nil}
-	req, err := buildGet(build)
+	req, err := r.buildGet(build)
 	if err != nil {
 		log.Error("fail to generate request: ", err)
 		return nil, err
 	}
 
-	respByte, err := request(*req, "fail to get build variable")
+	respByte, err := r.request(*req, "fail to get build variable")
 	if err != nil {
 		return nil, err
 	}
@@ -401,35 +525,55 @@ func GetBuildVar() (map[string]interface{}, error) {
 		return nil, err
 	}
 
-	buildVar := result.Data.(map[string]interface{})
+	buildVar, ok := result.Data.(map[string]interface{})
+	if !ok && result.Data != nil {
+		log.Error("unexpected response data: ", result.Data)
+		return nil, errors.New("unexpected response data")
+	}
 	return buildVar, nil
 }
 
+// GetBuildVar 获取指定构建下的构建参数
+func GetBuildVar() (map[string]interface{}, error) {
+	return DefaultRuntime().GetBuildVar()
+}
+
+// GetBuildContextByKey 获取指定构建下的构建上下文
+// Deprecated: 该接口将在后期弃用,请改用 GetVariableByName.
+func (r *Runtime) GetBuildContextByKey(key string) string {
+	return r.getVariable(key, false)
+}
+
 // GetBuildContextByKey 获取指定构建下的构建上下文
 // Deprecated: 该接口将在后期弃用,请改用 GetVariableByName.
 func GetBuildContextByKey(key string) string {
-	return getVariable(key, false)
+	return DefaultRuntime().GetBuildContextByKey(key)
+}
+
+// GetVariableByName 获取指定构建下的构建上下文新版
+func (r *Runtime) GetVariableByName(name string) string {
+	return r.getVariable(name, true)
 }
 
 // GetVariableByName 获取指定构建下的构建上下文新版
 func GetVariableByName(name string) string {
-	return getVariable(name, true)
+	return DefaultRuntime().GetVariableByName(name)
 }
 
-func getVariable(name string, check bool) string {
-	url := buildUrl("process/api/build/variable/get_build_context?contextName=" + name + "&check=" + fmt.Sprintf("%t", check))
-	headers := getAllHeaders()
-	headers["X-DEVOPS-BUILD-ID"] = gAtomBaseParam.PipelineBuildId
-	headers["X-DEVOPS-PROJECT-ID"] = gAtomBaseParam.ProjectName
-	headers["X-DEVOPS-PIPELINE-ID"] = gAtomBaseParam.PipelineId
+func (r *Runtime) getVariable(name string, check bool) string {
+	url := r.buildUrl("process/api/build/variable/get_build_context?contextName=" + name + "&check=" + fmt.Sprintf("%t", check))
+	headers := r.getAllHeaders()
+	headers["X-DEVOPS-BUILD-ID"] = r.AtomBaseParam.PipelineBuildId
+	headers["X-DEVOPS-PROJECT-ID"] = r.AtomBaseParam.ProjectName
+	headers["X-DEVOPS-PIPELINE-ID"] = r.AtomBaseParam.PipelineId
 	build := BuildRequest{path: url, headers: headers, requestBody: nil}
-	req, err := buildGet(build)
+	req, err := r.buildGet(build)
 	if err != nil {
 		log.Error("fail to generate request: ", err)
 		return ""
 	}
 
-	respByte, err := request(*req, "fail to get build context")
+	respByte, err := r.request(*req, "fail to get build context")
 	if err != nil {
 		return ""
 	}
@@ -441,6 +585,9 @@ func getVariable(name string, check bool) string {
 		log.Error("fail to unmarshal response message: ", err)
 		return ""
 	}
-	respStr := result.Data.(string)
+	respStr, ok := result.Data.(string)
+	if !ok && result.Data != nil {
+		log.Error("unexpected response data: ", result.Data)
+	}
 	return respStr
 }
@@ -0,0 +1,330 @@
+package api
+
+import (
+	"encoding"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// BindTag 输入绑定使用的结构体标签
+const BindTag = "bk"
+
+var (
+	errRequired         = errors.New("is required")
+	durationType        = reflect.TypeOf(time.Duration(0))
+	textUnmarshalerType = reflect.TypeOf((*encoding.TextUnmarshaler)(nil)).Elem()
+)
+
+// FieldError 输入参数绑定到结构体字段时的错误
+type FieldError struct {
+	Field string // 结构体字段名
+	Name  string // 输入参数名
+	Err   error
+}
+
+// Error 错误信息
+func (e *FieldError) Error() string {
+	return fmt.Sprintf("input %s %s", e.Name, e.Err.Error())
+}
+
+// Unwrap 获取被包装的错误
+func (e *FieldError) Unwrap() error {
+	return e.Err
+}
+
+// BindError 输入参数绑定的全部字段错误
+type BindError struct {
+	Fields []*FieldError
+}
+
+// Error 错误信息
+func (e *BindError) Error() string {
+	msgs := make([]string, 0, len(e.Fields))
+	for _, f := range e.Fields {
+		msgs = append(msgs, f.Error())
+	}
+	return strings.Join(msgs, "; ")
+}
+
+// bindOption 字段的绑定选项，来自 `bk:"name,required,default=3"`
+type bindOption struct {
+	name       string
+	required   bool
+	hasDefault bool
+	defaultVal string
+}
+
+// Bind 按结构体标签将输入参数填充到 v，v 必须为结构体指针
+// 标签格式为 `bk:"name,required,default=3"`，default 需放在最后，其值可以包含逗号
+// 未设置 bk 标签时使用 json 标签名或字段名，标签为 "-" 的字段跳过
+// 平台传入的字符串会按字段类型转换为 bool、整数、浮点数、time.Duration（纯数字视为秒），
+// 切片支持 JSON 数组或逗号分隔，map 与结构体支持 JSON
+func (r *Runtime) Bind(v interface{}) error {
+	rv := reflect.ValueOf(v)
+	if rv.Kind() != reflect.Ptr || rv.IsNil() || rv.Elem().Kind() != reflect.Struct {
+		return PluginErr(ErrorCodeDefault, "bind target must be a non-nil struct pointer")
+	}
+
+	berr := new(BindError)
+	r.bindStruct(berr, rv.Elem())
+	if len(berr.Fields) == 0 {
+		return nil
+	}
+	return UserErr(ErrorCodeInputInvalid, "invalid input").Wrap(berr)
+}
+
+// Bind 按结构体标签将输入参数填充到 v，v 必须为结构体指针
+func Bind(v interface{}) error {
+	return DefaultRuntime().Bind(v)
+}
+
+func (r *Runtime) bindStruct(berr *BindError, rv reflect.Value) {
+	rt := rv.Type()
+	for i := 0; i < rt.NumField(); i++ {
+		field := rt.Field(i)
+		fv := rv.Field(i)
+		if field.PkgPath != "" && !field.Anonymous {
+			continue
+		}
+
+		opt, ok := parseBindOption(field)
+		if !ok {
+			continue
+		}
+		if field.Anonymous && field.Type.Kind() == reflect.Struct && opt.name == "" {
+			r.bindStruct(berr, fv)
+			continue
+		}
+		if opt.name == "" {
+			opt.name = field.Name
+		}
+
+		raw, present := r.AllAtomParam[opt.name]
+		if !present || isEmptyInput(raw) {
+			switch {
+			case opt.hasDefault:
+				raw = opt.defaultVal
+			case opt.required:
+				berr.Fields = append(berr.Fields, &FieldError{Field: field.Name, Name: opt.name, Err: errRequired})
+				continue
+			default:
+				continue
+			}
+		}
+
+		if err := assign(fv, raw); err != nil {
+			berr.Fields = append(berr.Fields, &FieldError{Field: field.Name, Name: opt.name, Err: err})
+		}
+	}
+}
+
+func parseBindOption(field reflect.StructField) (*bindOption, bool) {
+	opt := new(bindOption)
+	tag, ok := field.Tag.Lookup(BindTag)
+	if !ok {
+		jsonTag := field.Tag.Get("json")
+		if jsonTag == "-" {
+			return nil, false
+		}
+		opt.name = strings.Split(jsonTag, ",")[0]
+		return opt, true
+	}
+	if tag == "-" {
+		return nil, false
+	}
+
+	parts := strings.Split(tag, ",")
+	opt.name = strings.TrimSpace(parts[0])
+	for i := 1; i < len(parts); i++ {
+		part := strings.TrimSpace(parts[i])
+		switch {
+		case part == "required":
+			opt.required = true
+		case strings.HasPrefix(part, "default="):
+			opt.hasDefault = true
+			opt.defaultVal = strings.TrimPrefix(strings.Join(parts[i:], ","), "default=")
+			return opt, true
+		}
+	}
+	return opt, true
+}
+
+func isEmptyInput(raw interface{}) bool {
+	switch v := raw.(type) {
+	case nil:
+		return true
+	case string:
+		return strings.TrimSpace(v) == ""
+	}
+	return false
+}
+
+// assign 将输入值转换为字段类型并赋值，null 赋为零值
+func assign(fv reflect.Value, raw interface{}) error {
+	if raw == nil {
+		fv.Set(reflect.Zero(fv.Type()))
+		return nil
+	}
+	if fv.Kind() == reflect.Ptr {
+		elem := reflect.New(fv.Type().Elem())
+		if err := assign(elem.Elem(), raw); err != nil {
+			return err
+		}
+		fv.Set(elem)
+		return nil
+	}
+
+	if s, ok := raw.(string); ok && fv.CanAddr() && fv.Addr().Type().Implements(textUnmarshalerType) {
+		return fv.Addr().Interface().(encoding.TextUnmarshaler).UnmarshalText([]byte(s))
+	}
+
+	if fv.Type() == durationType {
+		return assignDuration(fv, stringValue(raw))
+	}
+
+	switch fv.Kind() {
+	case reflect.Interface:
+		if fv.NumMethod() > 0 {
+			return fmt.Errorf("can not bind to interface %s", fv.Type())
+		}
+		fv.Set(reflect.ValueOf(raw))
+	case reflect.String:
+		fv.SetString(stringValue(raw))
+	case reflect.Bool:
+		b, err := strconv.ParseBool(strings.TrimSpace(stringValue(raw)))
+		if err != nil {
+			return fmt.Errorf("is not a boolean: %q", stringValue(raw))
+		}
+		fv.SetBool(b)
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		n, err := strconv.ParseInt(strings.TrimSpace(stringValue(raw)), 10, fv.Type().Bits())
+		if err != nil {
+			return fmt.Errorf("is not a valid %s: %q", fv.Type(), stringValue(raw))
+		}
+		fv.SetInt(n)
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		n, err := strconv.ParseUint(strings.TrimSpace(stringValue(raw)), 10, fv.Type().Bits())
+		if err != nil {
+			return fmt.Errorf("is not a valid %s: %q", fv.Type(), stringValue(raw))
+		}
+		fv.SetUint(n)
+	case reflect.Float32, reflect.Float64:
+		n, err := strconv.ParseFloat(strings.TrimSpace(stringValue(raw)), fv.Type().Bits())
+		if err != nil {
+			return fmt.Errorf("is not a valid %s: %q", fv.Type(), stringValue(raw))
+		}
+		fv.SetFloat(n)
+	case reflect.Slice:
+		return assignSlice(fv, raw)
+	case reflect.Map:
+		return assignMap(fv, raw)
+	default:
+		return assignJSON(fv, raw)
+	}
+	return nil
+}
+
+func assignDuration(fv reflect.Value, s string) error {
+	s = strings.TrimSpace(s)
+	if n, err := strconv.ParseInt(s, 10, 64); err == nil {
+		fv.SetInt(int64(time.Duration(n) * time.Second))
+		return nil
+	}
+	d, err := time.ParseDuration(s)
+	if err != nil {
+		return fmt.Errorf("is not a valid duration: %q", s)
+	}
+	fv.SetInt(int64(d))
+	return nil
+}
+
+func assignSlice(fv reflect.Value, raw interface{}) error {
+	var items []interface{}
+	switch v := raw.(type) {
+	case []interface{}:
+		items = v
+	case string:
+		s := strings.TrimSpace(v)
+		if strings.HasPrefix(s, "[") {
+			if err := json.Unmarshal([]byte(s), &items); err != nil {
+				return fmt.Errorf("is not a valid json array: %s", err.Error())
+			}
+			break
+		}
+		for _, item := range strings.Split(s, ",") {
+			if item = strings.TrimSpace(item); item != "" {
+				items = append(items, item)
+			}
+		}
+	default:
+		return assignJSON(fv, raw)
+	}
+
+	slice := reflect.MakeSlice(fv.Type(), len(items), len(items))
+	for i, item := range items {
+		if item == nil {
+			continue
+		}
+		if err := assign(slice.Index(i), item); err != nil {
+			return fmt.Errorf("[%d] %s", i, err.Error())
+		}
+	}
+	fv.Set(slice)
+	return nil
+}
+
+func assignMap(fv reflect.Value, raw interface{}) error {
+	var items map[string]interface{}
+	switch v := raw.(type) {
+	case map[string]interface{}:
+		items = v
+	case string:
+		if err := json.Unmarshal([]byte(v), &items); err != nil {
+			return fmt.Errorf("is not a valid json object: %s", err.Error())
+		}
+	default:
+		return assignJSON(fv, raw)
+	}
+	keyType := fv.Type().Key()
+	if keyType.Kind() != reflect.String {
+		return assignJSON(fv, items)
+	}
+
+	m := reflect.MakeMapWithSize(fv.Type(), len(items))
+	for k, item := range items {
+		elem := reflect.New(fv.Type().Elem()).Elem()
+		if item == nil {
+			m.SetMapIndex(reflect.ValueOf(k).Convert(keyType), elem)
+			continue
+		}
+		if err := assign(elem, item); err != nil {
+			return fmt.Errorf("[%s] %s", k, err.Error())
+		}
+		m.SetMapIndex(reflect.ValueOf(k).Convert(keyType), elem)
+	}
+	fv.Set(m)
+	return nil
+}
+
+// assignJSON 结构体等其他类型按 JSON 解析，字符串视为 JSON 文本
+func assignJSON(fv reflect.Value, raw interface{}) error {
+	data, ok := raw.(string)
+	if !ok {
+		b, err := json.Marshal(raw)
+		if err != nil {
+			return err
+		}
+		data = string(b)
+	}
+	ptr := reflect.New(fv.Type())
+	if err := json.Unmarshal([]byte(data), ptr.Interface()); err != nil {
+		return fmt.Errorf("is not a valid json for %s: %s", fv.Type(), err.Error())
+	}
+	fv.Set(ptr.Elem())
+	return nil
+}
@@ -0,0 +1,169 @@
+package api
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func bindRuntime(params map[string]interface{}) *Runtime {
+	r := newRuntime(nil)
+	r.AllAtomParam = params
+	return r
+}
+
+func TestBindConvert(t *testing.T) {
+	type input struct {
+		Name    string            `bk:"name"`
+		Enabled bool              `bk:"enabled"`
+		Count   int               `bk:"count"`
+		Ratio   float64           `bk:"ratio"`
+		Timeout time.Duration     `bk:"timeout"`
+		Wait    time.Duration     `bk:"wait"`
+		Tags    []string          `bk:"tags"`
+		Ports   []int             `bk:"ports"`
+		Labels  map[string]string `bk:"labels"`
+		Ptr     *int              `bk:"ptr"`
+		JSONTag string            `json:"json_tag"`
+		Skipped string            `bk:"-"`
+	}
+	r := bindRuntime(map[string]interface{}{
+		"name":     "demo",
+		"enabled":  "true",
+		"count":    "3",
+		"ratio":    1.5,
+		"timeout":  "30",
+		"wait":     "1m",
+		"tags":     "a, b,,c",
+		"ports":    "[80, 443]",
+		"labels":   `{"k":"v"}`,
+		"ptr":      "7",
+		"json_tag": "from json",
+		"-":        "ignored",
+	})
+
+	var got input
+	if err := r.Bind(&got); err != nil {
+		t.Fatalf("Bind() error = %v", err)
+	}
+	seven := 7
+	want := input{
+		Name:    "demo",
+		Enabled: true,
+		Count:   3,
+		Ratio:   1.5,
+		Timeout: 30 * time.Second,
+		Wait:    time.Minute,
+		Tags:    []string{"a", "b", "c"},
+		Ports:   []int{80, 443},
+		Labels:  map[string]string{"k": "v"},
+		Ptr:     &seven,
+		JSONTag: "from json",
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("Bind() = %+v, want %+v", got, want)
+	}
+}
+
+func TestBindDefaultAndRequired(t *testing.T) {
+	type input struct {
+		Mode  string `bk:"mode,default=a,b"`
+		Token string `bk:"token,required"`
+		Blank string `bk:"blank,required"`
+	}
+	r := bindRuntime(map[string]interface{}{"blank": "  "})
+
+	var got input
+	err := r.Bind(&got)
+	if got.Mode != "a,b" {
+		t.Errorf("Mode = %q, want default %q", got.Mode, "a,b")
+	}
+	var berr *BindError
+	if !errors.As(err, &berr) {
+		t.Fatalf("Bind() error = %v, want BindError", err)
+	}
+	if len(berr.Fields) != 2 || berr.Fields[0].Name != "token" || berr.Fields[1].Name != "blank" {
+		t.Errorf("Bind() fields = %v, want token and blank", berr)
+	}
+	var aerr *AtomError
+	if !errors.As(err, &aerr) || aerr.ErrorCode != ErrorCodeInputInvalid || aerr.ErrorType != UserError {
+		t.Errorf("Bind() error = %v, want user error %d", err, ErrorCodeInputInvalid)
+	}
+}
+
+func TestBindNull(t *testing.T) {
+	type input struct {
+		Items []interface{}          `bk:"items"`
+		Attrs map[string]interface{} `bk:"attrs"`
+		Nums  []int                  `bk:"nums"`
+		Names map[string]string      `bk:"names"`
+		Any   interface{}            `bk:"any"`
+	}
+	r := bindRuntime(map[string]interface{}{
+		"items": []interface{}{float64(1), nil},
+		"attrs": map[string]interface{}{"a": nil},
+		"nums":  "[1,null,3]",
+		"names": `{"a":null,"b":"x"}`,
+		"any":   []interface{}{nil},
+	})
+
+	var got input
+	if err := r.Bind(&got); err != nil {
+		t.Fatalf("Bind() error = %v", err)
+	}
+	want := input{
+		Items: []interface{}{float64(1), nil},
+		Attrs: map[string]interface{}{"a": nil},
+		Nums:  []int{1, 0, 3},
+		Names: map[string]string{"a": "", "b": "x"},
+		Any:   []interface{}{nil},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("Bind() = %#v, want %#v", got, want)
+	}
+}
+
+func TestBindInvalid(t *testing.T) {
+	tests := []struct {
+		name  string
+		raw   interface{}
+		field interface{}
+	}{
+		{"bool", "yes", new(struct {
+			V bool `bk:"v"`
+		})},
+		{"int", "1.5", new(struct {
+			V int `bk:"v"`
+		})},
+		{"int8 overflow", "300", new(struct {
+			V int8 `bk:"v"`
+		})},
+		{"duration", "soon", new(struct {
+			V time.Duration `bk:"v"`
+		})},
+		{"slice item", "[1,\"x\"]", new(struct {
+			V []int `bk:"v"`
+		})},
+		{"map", "not json", new(struct {
+			V map[string]int `bk:"v"`
+		})},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := bindRuntime(map[string]interface{}{"v": tt.raw})
+			if err := r.Bind(tt.field); err == nil {
+				t.Errorf("Bind(%v) error = nil, want error", tt.raw)
+			}
+		})
+	}
+}
+
+func TestBindTarget(t *testing.T) {
+	r := bindRuntime(map[string]interface{}{})
+	for _, v := range []interface{}{nil, struct{}{}, new(int), (*struct{})(nil)} {
+		if err := r.Bind(v); err == nil {
+			t.Errorf("Bind(%T) error = nil, want error", v)
+		}
+	}
+}
@@ -0,0 +1,143 @@
+package api
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/ci-plugins/golang-plugin-sdk/log"
+)
+
+// checkpointMessage 检查点输出的执行信息
+const checkpointMessage = "plugin did not finish, output is the last checkpoint"
+
+// EnableCheckpoint 开启输出检查点，插件未正常结束时输出文件中保留已设置的输出
+// interval 为 0 时每次输出变化都写入检查点，否则按间隔写入变化后的输出
+// 检查点的执行状态为 error，正常结束构建时会被最终输出覆盖
+func (r *Runtime) EnableCheckpoint(interval time.Duration) {
+	r.checkpointLock.Lock()
+	if r.checkpointEnabled {
+		r.checkpointLock.Unlock()
+		return
+	}
+	r.checkpointEnabled = true
+	r.checkpointInterval = interval
+	if interval > 0 {
+		r.checkpointStop = make(chan struct{})
+		go r.runCheckpoint(interval, r.checkpointStop)
+	}
+	r.checkpointLock.Unlock()
+
+	r.writeCheckpoint()
+}
+
+// EnableCheckpoint 开启输出检查点，插件未正常结束时输出文件中保留已设置的输出
+// interval 为 0 时每次输出变化都写入检查点，否则按间隔写入变化后的输出
+// 检查点的执行状态为 error，正常结束构建时会被最终输出覆盖
+func EnableCheckpoint(interval time.Duration) {
+	DefaultRuntime().EnableCheckpoint(interval)
+}
+
+// outputChanged 输出变化后写入检查点，按间隔写入时只标记有变化
+func (r *Runtime) outputChanged() {
+	r.checkpointLock.Lock()
+	if !r.checkpointEnabled {
+		r.checkpointLock.Unlock()
+		return
+	}
+	if r.checkpointInterval > 0 {
+		r.checkpointDirty = true
+		r.checkpointLock.Unlock()
+		return
+	}
+	r.checkpointLock.Unlock()
+
+	r.writeCheckpoint()
+}
+
+func (r *Runtime) runCheckpoint(interval time.Duration, stop chan struct{}) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-stop:
+			return
+		case <-ticker.C:
+			r.checkpointLock.Lock()
+			dirty := r.checkpointDirty
+			r.checkpointDirty = false
+			r.checkpointLock.Unlock()
+			if dirty {
+				r.writeCheckpoint()
+			}
+		}
+	}
+}
+
+// stopCheckpoint 结束构建时停止写入检查点
+func (r *Runtime) stopCheckpoint() {
+	r.checkpointLock.Lock()
+	defer r.checkpointLock.Unlock()
+	if r.checkpointStop != nil {
+		close(r.checkpointStop)
+		r.checkpointStop = nil
+	}
+	r.checkpointEnabled = false
+}
+
+// writeCheckpoint 以未结束状态写入当前输出，构建结束后不再写入
+func (r *Runtime) writeCheckpoint() {
+	r.writeLock.Lock()
+	defer r.writeLock.Unlock()
+	if r.isFinished() {
+		return
+	}
+
+	output := r.OutputSnapshot()
+	output.Status = StatusError
+	output.Message = checkpointMessage
+	output.ErrorCode = ErrorCodeNotFinished
+	output.ErrorType = PluginError
+	data, err := json.Marshal(output)
+	if err != nil {
+		// 保留上一次的检查点
+		log.Warn("marshal output checkpoint failed: ", err.Error())
+		return
+	}
+	if err = writeFileAtomic(r.OutputFilePath(), data); err != nil {
+		log.Warn("write output checkpoint failed: ", err.Error())
+	}
+}
+
+func (r *Runtime) isFinished() bool {
+	r.finishLock.Lock()
+	defer r.finishLock.Unlock()
+	return r.finished
+}
+
+// writeFileAtomic 先写入同目录下的临时文件再重命名，避免进程中断时留下不完整的文件
+func writeFileAtomic(path string, data []byte) error {
+	f, err := ioutil.TempFile(filepath.Dir(path), filepath.Base(path)+".tmp")
+	if err != nil {
+		return err
+	}
+	tmp := f.Name()
+	if _, err = f.Write(data); err == nil {
+		err = f.Sync()
+	}
+	if closeErr := f.Close(); err == nil {
+		err = closeErr
+	}
+	if err == nil {
+		err = os.Chmod(tmp, 0644)
+	}
+	if err == nil {
+		err = os.Rename(tmp, path)
+	}
+	if err != nil {
+		os.Remove(tmp)
+	}
+	return err
+}
@@ -0,0 +1,140 @@
+package api
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func readOutputFile(t *testing.T, r *Runtime) *AtomOutput {
+	t.Helper()
+	data, err := ioutil.ReadFile(r.OutputFilePath())
+	if err != nil {
+		t.Fatalf("read output: %v", err)
+	}
+	output := new(AtomOutput)
+	if err = json.Unmarshal(data, output); err != nil {
+		t.Fatalf("parse output %s: %v", data, err)
+	}
+	return output
+}
+
+// waitOutput 等待输出文件满足条件，检查点按间隔异步写入
+func waitOutput(t *testing.T, r *Runtime, ok func(output *AtomOutput) bool) *AtomOutput {
+	t.Helper()
+	deadline := time.Now().Add(2 * time.Second)
+	for {
+		output := readOutputFile(t, r)
+		if ok(output) || time.Now().After(deadline) {
+			return output
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+}
+
+func TestCheckpoint(t *testing.T) {
+	for _, interval := range []time.Duration{0, 10 * time.Millisecond} {
+		t.Run(interval.String(), func(t *testing.T) {
+			r := newRuntime(&RuntimeOptions{DataDir: t.TempDir(), Exiter: new(CaptureExiter)})
+			r.SetStringOutput("first", "1")
+			r.EnableCheckpoint(interval)
+			defer r.stopCheckpoint()
+
+			output := readOutputFile(t, r)
+			if output.Status != StatusError || output.ErrorCode != ErrorCodeNotFinished ||
+				output.ErrorType != PluginError || output.Message != checkpointMessage {
+				t.Errorf("checkpoint = %s/%d/%d %q", output.Status, output.ErrorCode, output.ErrorType, output.Message)
+			}
+			if output.Data["first"] == nil {
+				t.Errorf("checkpoint should contain outputs set before enabling")
+			}
+
+			r.SetStringOutput("second", "2")
+			output = waitOutput(t, r, func(output *AtomOutput) bool { return output.Data["second"] != nil })
+			if output.Data["second"] == nil {
+				t.Errorf("checkpoint was not rewritten after the output changed")
+			}
+		})
+	}
+}
+
+func TestCheckpointFinish(t *testing.T) {
+	exiter := new(CaptureExiter)
+	r := newRuntime(&RuntimeOptions{DataDir: t.TempDir(), Exiter: exiter})
+	r.EnableCheckpoint(time.Millisecond)
+	r.SetStringOutput("ver", "1")
+	r.FinishBuild(StatusSuccess, "done")
+
+	if r.checkpointStop != nil || r.checkpointEnabled {
+		t.Errorf("finish should stop the checkpoint")
+	}
+	output := readOutputFile(t, r)
+	if output.Status != StatusSuccess || output.Message != "done" || output.ErrorCode != 0 || output.Data["ver"] == nil {
+		t.Errorf("final output = %s/%d %q %v", output.Status, output.ErrorCode, output.Message, output.Data)
+	}
+
+	// 结束后设置输出与写入检查点都不再覆盖最终输出
+	r.SetStringOutput("late", "1")
+	r.writeCheckpoint()
+	time.Sleep(10 * time.Millisecond)
+	if output = readOutputFile(t, r); output.Status != StatusSuccess || output.Data["late"] != nil {
+		t.Errorf("output after finish = %s %v", output.Status, output.Data)
+	}
+}
+
+func TestCheckpointFailureKeepsPrevious(t *testing.T) {
+	r := newRuntime(&RuntimeOptions{DataDir: t.TempDir(), Exiter: new(CaptureExiter)})
+	r.SetStringOutput("ver", "1")
+	r.EnableCheckpoint(0)
+	previous, err := ioutil.ReadFile(r.OutputFilePath())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// 无法序列化的输出不覆盖上一次的检查点
+	r.AddOutputData("bad", func() {})
+	data, err := ioutil.ReadFile(r.OutputFilePath())
+	if err != nil || string(data) != string(previous) {
+		t.Errorf("checkpoint = %s, want previous %s", data, previous)
+	}
+	r.stopCheckpoint()
+}
+
+func TestWriteFileAtomic(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "output.json")
+	if err := writeFileAtomic(path, []byte("first")); err != nil {
+		t.Fatalf("writeFileAtomic() error = %v", err)
+	}
+	if err := writeFileAtomic(path, []byte("second")); err != nil {
+		t.Fatalf("writeFileAtomic() error = %v", err)
+	}
+	data, _ := ioutil.ReadFile(path)
+	info, _ := os.Stat(path)
+	if string(data) != "second" || info.Mode().Perm() != 0644 {
+		t.Errorf("file = %q, mode %v", data, info.Mode().Perm())
+	}
+
+	// 重命名失败时目标保持不变且不留下临时文件
+	target := filepath.Join(dir, "busy")
+	if err := os.MkdirAll(filepath.Join(target, "child"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := writeFileAtomic(target, []byte("x")); err == nil {
+		t.Errorf("writeFileAtomic() over a non-empty directory error = nil")
+	}
+	files, _ := ioutil.ReadDir(dir)
+	if len(files) != 2 {
+		var names []string
+		for _, f := range files {
+			names = append(names, f.Name())
+		}
+		t.Errorf("files after failed write = %v, want output.json and busy", names)
+	}
+	if _, err := os.Stat(filepath.Join(target, "child")); err != nil {
+		t.Errorf("failed write changed the target: %v", err)
+	}
+}
@@ -0,0 +1,74 @@
+package api
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/ci-plugins/golang-plugin-sdk/log"
+)
+
+// DefaultCleanupTimeout 单个清理逻辑默认的最长执行时间
+const DefaultCleanupTimeout = 10 * time.Second
+
+// CleanupFunc 清理逻辑，ctx 到期后应尽快返回
+type CleanupFunc func(ctx context.Context) error
+
+var (
+	gCleanupHooks   []CleanupFunc
+	gCleanupTimeout = DefaultCleanupTimeout
+	gCleanupLock    sync.Mutex
+)
+
+// RegisterCleanup 注册清理逻辑，在任意方式结束构建、写入输出并退出前按注册的逆序执行，
+// 包括 FinishBuild 系列函数、Run 返回、构建被取消以及运行时初始化失败
+func RegisterCleanup(fn CleanupFunc) {
+	gCleanupLock.Lock()
+	defer gCleanupLock.Unlock()
+	gCleanupHooks = append(gCleanupHooks, fn)
+}
+
+// SetCleanupTimeout 设置单个清理逻辑的最长执行时间，超时后不再等待并继续执行下一个
+func SetCleanupTimeout(d time.Duration) {
+	gCleanupLock.Lock()
+	defer gCleanupLock.Unlock()
+	gCleanupTimeout = d
+}
+
+// runCleanup 按注册的逆序执行并移除已注册的清理逻辑
+func runCleanup(ctx context.Context) {
+	gCleanupLock.Lock()
+	hooks := gCleanupHooks
+	timeout := gCleanupTimeout
+	gCleanupHooks = nil
+	gCleanupLock.Unlock()
+
+	for i := len(hooks) - 1; i >= 0; i-- {
+		if err := runCleanupHook(ctx, hooks[i], timeout); err != nil {
+			log.Error("cleanup failed: ", err.Error())
+		}
+	}
+}
+
+func runCleanupHook(ctx context.Context, fn CleanupFunc, timeout time.Duration) error {
+	hookCtx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	done := make(chan error, 1)
+	go func() {
+		defer func() {
+			if p := recover(); p != nil {
+				done <- fmt.Errorf("cleanup panic: %v", p)
+			}
+		}()
+		done <- fn(hookCtx)
+	}()
+
+	select {
+	case err := <-done:
+		return err
+	case <-hookCtx.Done():
+		return fmt.Errorf("cleanup did not finish: %s", hookCtx.Err().Error())
+	}
+}
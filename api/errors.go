@@ -0,0 +1,134 @@
+package api
+
+import (
+	"errors"
+
+	"github.com/ci-plugins/golang-plugin-sdk/log"
+)
+
+// AtomError 插件执行错误，携带结束构建所需的执行状态、错误码与错误类型
+// 由于 PluginError 已作为错误类型常量使用，这里沿用 Atom 前缀命名
+type AtomError struct {
+	Status            Status
+	ErrorCode         int
+	ErrorType         ErrorType
+	PlatformCode      string
+	PlatformErrorCode int
+	Message           string
+	Err               error
+}
+
+// NewAtomError 创建插件执行错误
+func NewAtomError(status Status, errorType ErrorType, errorCode int, msg string) *AtomError {
+	return &AtomError{
+		Status:    status,
+		ErrorCode: errorCode,
+		ErrorType: errorType,
+		Message:   msg,
+	}
+}
+
+// UserErr 创建用户错误，如输入参数不合法
+func UserErr(errorCode int, msg string) *AtomError {
+	return NewAtomError(StatusFailure, UserError, errorCode, msg)
+}
+
+// ThirdPartyErr 创建第三方错误，如依赖的外部服务异常
+func ThirdPartyErr(errorCode int, msg string) *AtomError {
+	return NewAtomError(StatusFailure, ThirdPartyError, errorCode, msg)
+}
+
+// PluginErr 创建插件自身错误
+func PluginErr(errorCode int, msg string) *AtomError {
+	return NewAtomError(StatusError, PluginError, errorCode, msg)
+}
+
+// Error 错误信息
+func (e *AtomError) Error() string {
+	if e.Err == nil {
+		return e.Message
+	}
+	if e.Message == "" {
+		return e.Err.Error()
+	}
+	return e.Message + ": " + e.Err.Error()
+}
+
+// Unwrap 获取被包装的错误
+func (e *AtomError) Unwrap() error {
+	return e.Err
+}
+
+// Is 错误类型与错误码相同即视为同一错误，便于使用 errors.Is 判断
+func (e *AtomError) Is(target error) bool {
+	t, ok := target.(*AtomError)
+	if !ok {
+		return false
+	}
+	return e.ErrorType == t.ErrorType && e.ErrorCode == t.ErrorCode
+}
+
+// Wrap 返回包装了 err 的错误副本，原错误不变，可用于预定义的错误
+func (e *AtomError) Wrap(err error) *AtomError {
+	c := *e
+	c.Err = err
+	return &c
+}
+
+// WithMessage 返回替换了错误信息的错误副本
+func (e *AtomError) WithMessage(msg string) *AtomError {
+	c := *e
+	c.Message = msg
+	return &c
+}
+
+// WithStatus 返回替换了执行状态的错误副本
+func (e *AtomError) WithStatus(status Status) *AtomError {
+	c := *e
+	c.Status = status
+	return &c
+}
+
+// WithPlatform 返回设置了对接平台代码与平台错误码的错误副本
+func (e *AtomError) WithPlatform(platformCode string, platformErrorCode int) *AtomError {
+	c := *e
+	c.PlatformCode = platformCode
+	c.PlatformErrorCode = platformErrorCode
+	return &c
+}
+
+// FinishBuildFromError 根据错误结束构建，err 为 nil 时以成功结束
+// 错误链中包含 AtomError 时使用其状态与错误码，否则视为插件错误
+func (r *Runtime) FinishBuildFromError(err error) {
+	if err == nil {
+		r.FinishBuild(StatusSuccess, r.OutputSnapshot().Message)
+		return
+	}
+
+	log.Error(err.Error())
+	var atomErr *AtomError
+	if !errors.As(err, &atomErr) {
+		atomErr = PluginErr(ErrorCodeDefault, "").Wrap(err)
+	}
+
+	status := atomErr.Status
+	if status == "" {
+		status = StatusFailure
+	}
+	r.finish(func(output *AtomOutput) {
+		output.Message = err.Error()
+		output.Status = status
+		output.ErrorCode = atomErr.ErrorCode
+		output.ErrorType = atomErr.ErrorType
+		if atomErr.PlatformCode != "" {
+			output.PlatformCode = atomErr.PlatformCode
+			output.PlatformErrorCode = atomErr.PlatformErrorCode
+		}
+	})
+}
+
+// FinishBuildFromError 根据错误结束构建，err 为 nil 时以成功结束
+// 错误链中包含 AtomError 时使用其状态与错误码，否则视为插件错误
+func FinishBuildFromError(err error) {
+	DefaultRuntime().FinishBuildFromError(err)
+}
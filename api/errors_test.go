@@ -0,0 +1,62 @@
+package api
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+)
+
+func TestErrCancelledIs(t *testing.T) {
+	tests := []struct {
+		name string
+		err  error
+		want bool
+	}{
+		{"cancelled", ErrCancelled.WithMessage("build cancelled by signal terminated"), true},
+		{"wrapped", PluginErr(ErrorCodeDefault, "run").Wrap(ErrCancelled), true},
+		{"user error", UserErr(ErrorCodeDefault, "bad input"), false},
+		{"same code other type", PluginErr(ErrorCodeCancelled, "x"), false},
+		{"plain", errors.New("build cancelled"), false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := errors.Is(tt.err, ErrCancelled); got != tt.want {
+				t.Errorf("errors.Is(%v, ErrCancelled) = %v, want %v", tt.err, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestFinishBuildFromError(t *testing.T) {
+	tests := []struct {
+		name      string
+		err       error
+		status    Status
+		code      int
+		errorType ErrorType
+		exitCode  int
+	}{
+		{"nil", nil, StatusSuccess, 0, 0, 0},
+		{"plain", errors.New("boom"), StatusError, ErrorCodeDefault, PluginError, 2},
+		{"plugin", PluginErr(ErrorCodeDefault, "boom"), StatusError, ErrorCodeDefault, PluginError, 2},
+		{"user", UserErr(ErrorCodeInputInvalid, "bad"), StatusFailure, ErrorCodeInputInvalid, UserError, 1},
+		{"third party", ThirdPartyErr(42, "down"), StatusFailure, 42, ThirdPartyError, 1},
+		{"wrapped", fmt.Errorf("call: %w", UserErr(7, "bad")), StatusFailure, 7, UserError, 1},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			exiter := new(CaptureExiter)
+			r := newRuntime(&RuntimeOptions{DataDir: t.TempDir(), OutputFile: "output.json", Exiter: exiter})
+			r.FinishBuildFromError(tt.err)
+
+			output := exiter.Output()
+			if output.Status != tt.status || output.ErrorCode != tt.code || output.ErrorType != tt.errorType {
+				t.Errorf("output = %s/%d/%d, want %s/%d/%d",
+					output.Status, output.ErrorCode, output.ErrorType, tt.status, tt.code, tt.errorType)
+			}
+			if exiter.Code() != tt.exitCode {
+				t.Errorf("exit code = %d, want %d", exiter.Code(), tt.exitCode)
+			}
+		})
+	}
+}
@@ -0,0 +1,123 @@
+package api
+
+import (
+	"os"
+	"sync"
+)
+
+// Exiter 结束构建时的退出处理，写入输出文件后调用
+type Exiter interface {
+	Exit(status Status, code int, output *AtomOutput)
+}
+
+// ExiterFunc 函数形式的退出处理
+type ExiterFunc func(status Status, code int, output *AtomOutput)
+
+// Exit 退出
+func (f ExiterFunc) Exit(status Status, code int, output *AtomOutput) {
+	f(status, code, output)
+}
+
+// osExiter 使用 os.Exit 退出进程
+type osExiter struct{}
+
+func (osExiter) Exit(status Status, code int, output *AtomOutput) {
+	os.Exit(code)
+}
+
+var (
+	gExiter     Exiter = osExiter{}
+	gExiterLock sync.RWMutex
+)
+
+// SetExiter 设置未单独指定退出处理的运行时使用的退出处理，传入 nil 时恢复为 os.Exit，返回原来的退出处理
+func SetExiter(e Exiter) Exiter {
+	if e == nil {
+		e = osExiter{}
+	}
+	gExiterLock.Lock()
+	defer gExiterLock.Unlock()
+	old := gExiter
+	gExiter = e
+	return old
+}
+
+func defaultExiter() Exiter {
+	gExiterLock.RLock()
+	defer gExiterLock.RUnlock()
+	return gExiter
+}
+
+// SetExiter 设置当前运行时的退出处理
+func (r *Runtime) SetExiter(e Exiter) {
+	r.exiter = e
+}
+
+func (r *Runtime) exit() {
+	e := r.exiter
+	if e == nil {
+		e = defaultExiter()
+	}
+	output := r.OutputSnapshot()
+	e.Exit(output.Status, exitCode(output.Status), output)
+}
+
+func exitCode(status Status) int {
+	switch status {
+	case StatusSuccess:
+		return 0
+	case StatusFailure:
+		return 1
+	case StatusError:
+		return 2
+	default:
+		return 0
+	}
+}
+
+// CaptureExiter 记录退出信息而不退出进程，用于测试
+type CaptureExiter struct {
+	lock   sync.Mutex
+	exited bool
+	status Status
+	code   int
+	output *AtomOutput
+}
+
+// Exit 记录退出信息
+func (c *CaptureExiter) Exit(status Status, code int, output *AtomOutput) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	c.exited = true
+	c.status = status
+	c.code = code
+	c.output = output
+}
+
+// Exited 是否已结束构建
+func (c *CaptureExiter) Exited() bool {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	return c.exited
+}
+
+// Status 结束构建时的任务状态
+func (c *CaptureExiter) Status() Status {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	return c.status
+}
+
+// Code 结束构建时的退出码
+func (c *CaptureExiter) Code() int {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	return c.code
+}
+
+// Output 结束构建时的插件输出
+func (c *CaptureExiter) Output() *AtomOutput {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	return c.output
+}
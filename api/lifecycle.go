@@ -0,0 +1,87 @@
+package api
+
+import (
+	"fmt"
+
+	"github.com/ci-plugins/golang-plugin-sdk/log"
+)
+
+// OnMain 注册主流程的执行逻辑
+func (r *Runtime) OnMain(fn HandlerFunc) {
+	r.mainHandler = fn
+}
+
+// OnMain 注册主流程的执行逻辑
+func OnMain(fn HandlerFunc) {
+	DefaultRuntime().OnMain(fn)
+}
+
+// OnPost 注册后置动作的执行逻辑，name 对应 task.json 中 execution.post.postEntryParam
+func (r *Runtime) OnPost(name string, fn HandlerFunc) {
+	if r.postHandlers == nil {
+		r.postHandlers = make(map[string]HandlerFunc)
+	}
+	r.postHandlers[name] = fn
+}
+
+// OnPost 注册后置动作的执行逻辑，name 对应 task.json 中 execution.post.postEntryParam
+func OnPost(name string, fn HandlerFunc) {
+	DefaultRuntime().OnPost(name, fn)
+}
+
+// PostAction 获取当前执行的后置动作，执行主流程时返回空字符串
+// 优先使用 -postAction 命令行参数，未指定时使用输入参数中的 postEntryParam
+func (r *Runtime) PostAction() string {
+	if r.postAction != "" && r.postAction != NoPostAction {
+		return r.postAction
+	}
+	return r.postEntryParam
+}
+
+// PostAction 获取当前执行的后置动作，执行主流程时返回空字符串
+func PostAction() string {
+	return DefaultRuntime().PostAction()
+}
+
+// Start 根据当前阶段执行已注册的主流程或后置动作，并结束构建
+func (r *Runtime) Start() {
+	name := r.PostAction()
+	if name == "" {
+		if r.mainHandler == nil {
+			r.FinishBuildFromError(PluginErr(ErrorCodeDefault, "no main handler registered"))
+			return
+		}
+		r.runPhase("main", r.mainHandler, true)
+		return
+	}
+
+	fn, ok := r.postHandlers[name]
+	if !ok {
+		r.FinishBuildFromError(PluginErr(ErrorCodeDefault, fmt.Sprintf("no handler registered for post action %s", name)))
+		return
+	}
+	r.runPhase("post action "+name, fn, false)
+}
+
+// Start 根据当前阶段执行已注册的主流程或后置动作，并结束构建
+func Start() {
+	DefaultRuntime().Start()
+}
+
+// runPhase 在日志分组中执行指定阶段的逻辑，主流程执行前会校验输入
+func (r *Runtime) runPhase(phase string, fn HandlerFunc, validate bool) {
+	log.Group(phase)
+	var err error
+	if validate {
+		err = r.ValidateInput()
+	}
+	if err == nil {
+		err = r.call(fn)
+	}
+	log.EndGroup()
+
+	if err != nil {
+		log.Errorf("%s failed", phase)
+	}
+	r.FinishBuildFromError(err)
+}
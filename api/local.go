@@ -0,0 +1,193 @@
+package api
+
+import (
+	"encoding/json"
+	"flag"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strconv"
+	"strings"
+
+	"github.com/ci-plugins/golang-plugin-sdk/log"
+)
+
+// 本地运行模式相关的环境变量
+const (
+	LocalEnv       = "BK_CI_LOCAL"        // 为 true 时启用本地运行模式
+	LocalConfigEnv = "BK_CI_LOCAL_CONFIG" // 本地运行模式的配置文件路径
+
+	LocalGatewayEnv   = "BK_CI_GATEWAY"
+	LocalProjectEnv   = "BK_CI_PROJECT_NAME"
+	LocalBuildEnv     = "BK_CI_BUILD_ID"
+	LocalTaskEnv      = "BK_CI_BUILD_TASK_ID"
+	LocalBuildTypeEnv = "BK_CI_BUILD_TYPE"
+	LocalAgentEnv     = "BK_CI_AGENT_ID"
+	LocalSecretKeyEnv = "BK_CI_AGENT_SECRET_KEY"
+	LocalVmSeqEnv     = "BK_CI_VM_SEQ_ID"
+)
+
+var localFlag = flag.Bool("bk-local", false, "本地运行模式，同环境变量 BK_CI_LOCAL")
+
+// LocalConfig 本地运行模式的配置文件内容
+type LocalConfig struct {
+	SdkEnv *SdkEnv                `json:"sdkEnv"`
+	Input  map[string]interface{} `json:"input"`
+}
+
+// IsLocal 是否处于本地运行模式
+func (r *Runtime) IsLocal() bool {
+	return r.local
+}
+
+// IsLocal 是否处于本地运行模式
+func IsLocal() bool {
+	return DefaultRuntime().IsLocal()
+}
+
+func localEnabled() bool {
+	if *localFlag {
+		return true
+	}
+	enabled, _ := strconv.ParseBool(strings.TrimSpace(os.Getenv(LocalEnv)))
+	return enabled
+}
+
+// loadLocal 本地运行模式下依次使用 .sdk.json 与 input.json、配置文件、环境变量中的值，文件缺失时忽略
+func (r *Runtime) loadLocal() error {
+	config := new(LocalConfig)
+	if r.localConfig != "" {
+		data, err := ioutil.ReadFile(r.localConfig)
+		if err != nil {
+			log.Error("read local config failed: ", err.Error())
+			return PluginErr(ErrorCodeInitFailed, "read local config failed")
+		}
+		if err = json.Unmarshal(data, config); err != nil {
+			log.Error("parse local config failed: ", err.Error())
+			return PluginErr(ErrorCodeInitFailed, "parse local config failed")
+		}
+	}
+
+	if _, err := os.Stat(filepath.Join(r.dataDir, ".sdk.json")); err == nil {
+		if err = r.loadSdkEnv(); err != nil {
+			return err
+		}
+	}
+	if config.SdkEnv != nil {
+		mergeSdkEnv(r.SdkEnv, config.SdkEnv)
+	}
+	mergeSdkEnv(r.SdkEnv, &SdkEnv{
+		Gateway:   os.Getenv(LocalGatewayEnv),
+		ProjectId: os.Getenv(LocalProjectEnv),
+		BuildId:   os.Getenv(LocalBuildEnv),
+		TaskId:    os.Getenv(LocalTaskEnv),
+		BuildType: os.Getenv(LocalBuildTypeEnv),
+		AgentId:   os.Getenv(LocalAgentEnv),
+		SecretKey: os.Getenv(LocalSecretKeyEnv),
+		VmSeqId:   os.Getenv(LocalVmSeqEnv),
+	})
+	if r.SdkEnv.BuildType == "" {
+		r.SdkEnv.BuildType = BuildTypeWorker
+	}
+
+	if _, err := os.Stat(r.InputFilePath()); err == nil {
+		if err = r.LoadInputParam(&r.AllAtomParam); err != nil {
+			log.Error("init atom base param failed: ", err.Error())
+			return PluginErr(ErrorCodeInitFailed, "init atom base param failed")
+		}
+	}
+	for k, v := range config.Input {
+		r.AllAtomParam[k] = v
+	}
+	// 基本参数未设置时取同名环境变量
+	baseType := reflect.TypeOf(AtomBaseParam{})
+	for i := 0; i < baseType.NumField(); i++ {
+		name := strings.Split(baseType.Field(i).Tag.Get("json"), ",")[0]
+		if _, ok := r.AllAtomParam[name]; ok {
+			continue
+		}
+		if value, ok := os.LookupEnv(name); ok {
+			r.AllAtomParam[name] = value
+		}
+	}
+	if _, ok := r.AllAtomParam["bkWorkspace"]; !ok {
+		r.AllAtomParam["bkWorkspace"], _ = os.Getwd()
+	}
+
+	baseParam := new(AtomBaseParam)
+	if err := r.LoadInputParam(baseParam); err != nil {
+		log.Error("init atom base param failed: ", err.Error())
+		return PluginErr(ErrorCodeInitFailed, "init atom base param failed")
+	}
+	r.postEntryParam = baseParam.PostActionParam
+	baseParam.PostActionParam = r.postAction
+	r.AtomBaseParam = baseParam
+
+	if strings.TrimSpace(r.SdkEnv.Gateway) == "" {
+		log.Warn("local mode: gateway not configured, remote calls will be stubbed")
+		// 只替换当前运行时的 transport，SetHTTPTransport 设置的全局 transport 优先
+		if client.Transport == nil {
+			r.transport = stubTransport{}
+		}
+	}
+	return nil
+}
+
+// loadLocalInput 本地运行模式下输入文件不存在时从已加载的输入参数解析
+func (r *Runtime) loadLocalInput(v interface{}) error {
+	data, err := json.Marshal(r.AllAtomParam)
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(data, v)
+}
+
+func mergeSdkEnv(dst *SdkEnv, src *SdkEnv) {
+	set := func(dst *string, src string) {
+		if src = strings.TrimSpace(src); src != "" {
+			*dst = src
+		}
+	}
+	set(&dst.Gateway, src.Gateway)
+	set(&dst.ProjectId, src.ProjectId)
+	set(&dst.BuildId, src.BuildId)
+	set(&dst.TaskId, src.TaskId)
+	set(&dst.BuildType, src.BuildType)
+	set(&dst.AgentId, src.AgentId)
+	set(&dst.SecretKey, src.SecretKey)
+	set(&dst.VmSeqId, src.VmSeqId)
+}
+
+// printLocalOutput 本地运行模式下打印插件输出
+func (r *Runtime) printLocalOutput() {
+	data, err := json.MarshalIndent(r.OutputSnapshot(), "", "  ")
+	if err != nil {
+		log.Error("marshal output failed: ", err.Error())
+		return
+	}
+	log.Group("output.json")
+	log.Info("\n" + string(data))
+	log.EndGroup()
+}
+
+// stubTransport 本地运行模式下未配置网关时使用，所有请求返回空数据
+type stubTransport struct{}
+
+// RoundTrip 返回空数据
+func (stubTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	log.Warnf("local mode: stub request %s %s", req.Method, req.URL.Path)
+	body := `{"status":0,"data":null}`
+	return &http.Response{
+		Status:        "200 OK",
+		StatusCode:    http.StatusOK,
+		Proto:         "HTTP/1.1",
+		ProtoMajor:    1,
+		ProtoMinor:    1,
+		Header:        http.Header{"Content-Type": []string{"application/json"}},
+		Body:          ioutil.NopCloser(strings.NewReader(body)),
+		ContentLength: int64(len(body)),
+		Request:       req,
+	}, nil
+}
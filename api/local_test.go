@@ -0,0 +1,41 @@
+package api
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestLocalFromEnv(t *testing.T) {
+	dir := t.TempDir()
+	config := filepath.Join(dir, "local.json")
+	if err := os.WriteFile(config, []byte(`{"input": {"name": "demo"}}`), 0644); err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv(LocalEnv, "true")
+	t.Setenv(LocalConfigEnv, config)
+
+	r, err := NewRuntime(&RuntimeOptions{DataDir: dir})
+	if err != nil {
+		t.Fatalf("NewRuntime() error = %v", err)
+	}
+	if !r.IsLocal() || r.localConfig != config {
+		t.Errorf("local = %v, localConfig = %q, want env values", r.IsLocal(), r.localConfig)
+	}
+	if got := r.GetInputParam("name"); got != "demo" {
+		t.Errorf("GetInputParam(name) = %q, want demo", got)
+	}
+
+	// 未配置网关时只有本地运行时使用空数据的 transport
+	if _, ok := r.transport.(stubTransport); !ok || client.Transport != nil {
+		t.Errorf("stub transport should be scoped to the local runtime, runtime %T, global %T", r.transport, client.Transport)
+	}
+	if data, err := r.GetGitOauth("user"); err != nil || data != nil {
+		t.Errorf("stubbed GetGitOauth() = %v, %v", data, err)
+	}
+
+	t.Setenv(LocalEnv, "")
+	if _, err = NewRuntime(&RuntimeOptions{DataDir: dir}); err == nil {
+		t.Errorf("NewRuntime() without .sdk.json outside local mode error = nil")
+	}
+}
@@ -0,0 +1,69 @@
+package api
+
+import "strings"
+
+// NamespaceInput 输出命名空间对应的输入参数，同一 Job 中多次使用同一插件时用于区分输出变量
+// 蓝盾 worker 读取 output.json 时会按该输入为输出变量加上命名空间前缀，
+// 因此 SDK 默认不修改输出名称，worker 不支持时可通过 SetNamespaceOutputs 开启
+const NamespaceInput = "namespace"
+
+// Namespace 获取输出命名空间，未设置时返回空字符串
+func (r *Runtime) Namespace() string {
+	return strings.TrimSpace(r.GetInputParam(NamespaceInput))
+}
+
+// Namespace 获取输出命名空间，未设置时返回空字符串
+func Namespace() string {
+	return DefaultRuntime().Namespace()
+}
+
+// SetNamespaceOutputs 设置是否由 SDK 为输出名称加上命名空间前缀，默认不添加
+func (r *Runtime) SetNamespaceOutputs(enabled bool) {
+	r.namespaceOutputs = enabled
+}
+
+// SetNamespaceOutputs 设置是否由 SDK 为输出名称加上命名空间前缀，默认不添加
+func SetNamespaceOutputs(enabled bool) {
+	DefaultRuntime().SetNamespaceOutputs(enabled)
+}
+
+// NamespacedKey 获取输出在流水线中的变量名，设置了命名空间时为 <namespace>_<key>
+func (r *Runtime) NamespacedKey(key string) string {
+	ns := r.Namespace()
+	if ns == "" {
+		return key
+	}
+	return ns + "_" + key
+}
+
+// NamespacedKey 获取输出在流水线中的变量名，设置了命名空间时为 <namespace>_<key>
+func NamespacedKey(key string) string {
+	return DefaultRuntime().NamespacedKey(key)
+}
+
+// OutputVarRef 获取下游插件引用该输出的变量表达式，如 ${namespace_key}
+func (r *Runtime) OutputVarRef(key string) string {
+	return "${" + r.NamespacedKey(key) + "}"
+}
+
+// OutputVarRef 获取下游插件引用该输出的变量表达式，如 ${namespace_key}
+func OutputVarRef(key string) string {
+	return DefaultRuntime().OutputVarRef(key)
+}
+
+// outputKey 输出写入 output.json 时使用的名称，开启 SDK 添加命名空间前缀时为 NamespacedKey
+func (r *Runtime) outputKey(key string) string {
+	if !r.namespaceOutputs {
+		return key
+	}
+	return r.NamespacedKey(key)
+}
+
+// outputName 去掉 SDK 添加的命名空间前缀，得到 task.json 中声明的输出名称
+func (r *Runtime) outputName(key string) string {
+	ns := r.Namespace()
+	if !r.namespaceOutputs || ns == "" {
+		return key
+	}
+	return strings.TrimPrefix(key, ns+"_")
+}
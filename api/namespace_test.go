@@ -0,0 +1,50 @@
+package api
+
+import "testing"
+
+func TestNamespacedKey(t *testing.T) {
+	tests := []struct {
+		namespace string
+		key       string
+		want      string
+	}{
+		{"", "build_id", "build_id"},
+		{"build", "build_id", "build_build_id"},
+		{"first", "ver", "first_ver"},
+		{" first ", "ver", "first_ver"},
+	}
+	for _, tt := range tests {
+		r := newRuntime(nil)
+		r.AllAtomParam[NamespaceInput] = tt.namespace
+		if got := r.NamespacedKey(tt.key); got != tt.want {
+			t.Errorf("NamespacedKey(%q) with namespace %q = %q, want %q", tt.key, tt.namespace, got, tt.want)
+		}
+		if got, want := r.OutputVarRef(tt.key), "${"+tt.want+"}"; got != want {
+			t.Errorf("OutputVarRef(%q) with namespace %q = %q, want %q", tt.key, tt.namespace, got, want)
+		}
+	}
+}
+
+func TestNamespaceOutputs(t *testing.T) {
+	for _, enabled := range []bool{false, true} {
+		r := newRuntime(&RuntimeOptions{NamespaceOutputs: enabled})
+		r.AllAtomParam[NamespaceInput] = "build"
+		r.SetTask(validateTask(t))
+		r.SetStringOutput("ver", "1")
+		r.AddArtifactOutput("pkg", "a")
+
+		key := "ver"
+		if enabled {
+			key = "build_ver"
+		}
+		if _, ok := r.OutputSnapshot().Data[key]; !ok {
+			t.Errorf("enabled=%v: output keys = %v, want %s", enabled, r.OutputSnapshot().Data, key)
+		}
+		if got := r.GetStringOutput("ver"); got == nil || got.Value != "1" {
+			t.Errorf("enabled=%v: GetStringOutput() = %v", enabled, got)
+		}
+		if err := r.ValidateOutput(); err != nil {
+			t.Errorf("enabled=%v: ValidateOutput() error = %v", enabled, err)
+		}
+	}
+}
@@ -0,0 +1,119 @@
+package api
+
+import (
+	"encoding/json"
+	"fmt"
+)
+
+// SetStringOutput 设置变量输出
+func (r *Runtime) SetStringOutput(key string, value string) {
+	r.AddOutputData(key, NewStringData(value))
+}
+
+// SetStringOutput 设置变量输出
+func SetStringOutput(key string, value string) {
+	DefaultRuntime().SetStringOutput(key, value)
+}
+
+// AddArtifactOutput 添加待归档构件输出，输出已存在时追加到已有的构件输出中，返回添加后构件输出的副本
+func (r *Runtime) AddArtifactOutput(key string, artifacts ...string) *ArtifactData {
+	key = r.outputKey(key)
+	var data *ArtifactData
+	r.updateOutput(func(output *AtomOutput) {
+		data, _ = output.Data[key].(*ArtifactData)
+		if data == nil {
+			data = NewArtifactData()
+			output.Data[key] = data
+		}
+		data.AddArtifactAll(artifacts)
+		data = cloneOutputData(data).(*ArtifactData)
+	})
+	return data
+}
+
+// AddArtifactOutput 添加待归档构件输出，输出已存在时追加到已有的构件输出中，返回添加后构件输出的副本
+func AddArtifactOutput(key string, artifacts ...string) *ArtifactData {
+	return DefaultRuntime().AddArtifactOutput(key, artifacts...)
+}
+
+// AddReportOutput 添加报告输出，报告使用 NewInternalReportData 或 NewThirdpartyReportData 创建
+func (r *Runtime) AddReportOutput(key string, report *ReportData) {
+	r.AddOutputData(key, report)
+}
+
+// AddReportOutput 添加报告输出，报告使用 NewInternalReportData 或 NewThirdpartyReportData 创建
+func AddReportOutput(key string, report *ReportData) {
+	DefaultRuntime().AddReportOutput(key, report)
+}
+
+// SetJSONOutput 将 value 序列化为 JSON 后设置为变量输出
+func (r *Runtime) SetJSONOutput(key string, value interface{}) error {
+	data, err := json.Marshal(value)
+	if err != nil {
+		return PluginErr(ErrorCodeDefault, fmt.Sprintf("marshal output %s failed", key)).Wrap(err)
+	}
+	r.SetStringOutput(key, string(data))
+	return nil
+}
+
+// SetJSONOutput 将 value 序列化为 JSON 后设置为变量输出
+func SetJSONOutput[T any](key string, value T) error {
+	return DefaultRuntime().SetJSONOutput(key, value)
+}
+
+// GetStringOutput 获取变量输出，输出不存在或不是变量输出时返回 nil，返回的是副本
+func (r *Runtime) GetStringOutput(key string) *StringData {
+	data, _ := r.outputCopy(key).(*StringData)
+	return data
+}
+
+// GetStringOutput 获取变量输出，输出不存在或不是变量输出时返回 nil，返回的是副本
+func GetStringOutput(key string) *StringData {
+	return DefaultRuntime().GetStringOutput(key)
+}
+
+// GetArtifactOutput 获取构件输出，输出不存在或不是构件输出时返回 nil，返回的是副本
+func (r *Runtime) GetArtifactOutput(key string) *ArtifactData {
+	data, _ := r.outputCopy(key).(*ArtifactData)
+	return data
+}
+
+// GetArtifactOutput 获取构件输出，输出不存在或不是构件输出时返回 nil，返回的是副本
+func GetArtifactOutput(key string) *ArtifactData {
+	return DefaultRuntime().GetArtifactOutput(key)
+}
+
+// GetReportOutput 获取报告输出，输出不存在或不是报告输出时返回 nil，返回的是副本
+func (r *Runtime) GetReportOutput(key string) *ReportData {
+	data, _ := r.outputCopy(key).(*ReportData)
+	return data
+}
+
+// GetReportOutput 获取报告输出，输出不存在或不是报告输出时返回 nil，返回的是副本
+func GetReportOutput(key string) *ReportData {
+	return DefaultRuntime().GetReportOutput(key)
+}
+
+// GetJSONOutput 将 SetJSONOutput 设置的变量输出反序列化到 v 中
+func (r *Runtime) GetJSONOutput(key string, v interface{}) error {
+	data := r.GetStringOutput(key)
+	if data == nil {
+		return fmt.Errorf("string output %s not found", key)
+	}
+	return json.Unmarshal([]byte(data.Value), v)
+}
+
+// GetJSONOutput 获取 SetJSONOutput 设置的变量输出并反序列化为 T
+func GetJSONOutput[T any](key string) (T, error) {
+	var value T
+	err := DefaultRuntime().GetJSONOutput(key, &value)
+	return value, err
+}
+
+// outputCopy 在锁内复制输出，供类型化的获取方法使用
+func (r *Runtime) outputCopy(key string) interface{} {
+	key = r.outputKey(key)
+	r.outputLock.RLock()
+	defer r.outputLock.RUnlock()
+	return cloneOutputData(r.AtomOutput.Data[key])
+}
@@ -0,0 +1,98 @@
+package api
+
+import (
+	"fmt"
+	"reflect"
+	"sync"
+	"testing"
+)
+
+func TestTypedOutput(t *testing.T) {
+	r := newRuntime(nil)
+	r.SetStringOutput("s", "v")
+	r.AddArtifactOutput("a", "x")
+	r.AddArtifactOutput("a", "y", "z")
+	r.AddReportOutput("r", NewThirdpartyReportData("label", "http://example.com"))
+	if err := r.SetJSONOutput("j", map[string]int{"n": 1}); err != nil {
+		t.Fatalf("SetJSONOutput() error = %v", err)
+	}
+
+	if got := r.GetStringOutput("s"); got == nil || got.Value != "v" {
+		t.Errorf("GetStringOutput() = %v", got)
+	}
+	if got := r.GetArtifactOutput("a"); got == nil || !reflect.DeepEqual(got.Value, []string{"x", "y", "z"}) {
+		t.Errorf("GetArtifactOutput() = %v", got)
+	}
+	if got := r.GetReportOutput("r"); got == nil || got.Url != "http://example.com" {
+		t.Errorf("GetReportOutput() = %v", got)
+	}
+	var j map[string]int
+	if err := r.GetJSONOutput("j", &j); err != nil || j["n"] != 1 {
+		t.Errorf("GetJSONOutput() = %v, %v", j, err)
+	}
+	if r.GetArtifactOutput("s") != nil || r.GetStringOutput("missing") != nil {
+		t.Errorf("getter with wrong type or missing key should return nil")
+	}
+}
+
+func TestOutputGettersReturnCopies(t *testing.T) {
+	r := newRuntime(nil)
+	added := r.AddArtifactOutput("a", "x")
+	added.AddArtifact("changed")
+	got := r.GetArtifactOutput("a")
+	got.AddArtifact("changed")
+
+	if want := []string{"x"}; !reflect.DeepEqual(r.GetArtifactOutput("a").Value, want) {
+		t.Errorf("stored artifacts = %v, want %v", r.GetArtifactOutput("a").Value, want)
+	}
+
+	snapshot := r.OutputSnapshot()
+	snapshot.Data["a"].(*ArtifactData).AddArtifact("changed")
+	snapshot.Data["new"] = NewStringData("1")
+	if r.GetOutputData("new") != nil || len(r.GetArtifactOutput("a").Value) != 1 {
+		t.Errorf("modifying the snapshot changed the runtime output")
+	}
+}
+
+func TestGetOutputDataInPlace(t *testing.T) {
+	r := newRuntime(nil)
+	r.AddOutputData("pkg", NewArtifactData())
+	r.GetOutputData("pkg").(*ArtifactData).AddArtifact("x")
+	if want := []string{"x"}; !reflect.DeepEqual(r.GetArtifactOutput("pkg").Value, want) {
+		t.Errorf("stored artifacts = %v, want %v", r.GetArtifactOutput("pkg").Value, want)
+	}
+
+	r.AddQualityData("q", NewQualityData("1"))
+	r.GetQualityData("q").(*Qualitydata).Value = "2"
+	if got := r.OutputSnapshot().QualityData["q"].Value; got != "2" {
+		t.Errorf("quality value = %s, want 2", got)
+	}
+}
+
+// TestConcurrentOutput 需配合 -race 运行
+func TestConcurrentOutput(t *testing.T) {
+	r := newRuntime(&RuntimeOptions{DataDir: t.TempDir()})
+	var wg sync.WaitGroup
+	for i := 0; i < 20; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			r.SetStringOutput(fmt.Sprint("s", i), "v")
+			r.AddArtifactOutput("a", fmt.Sprint(i)).AddArtifact("local")
+			if data := r.GetArtifactOutput("a"); data != nil {
+				data.AddArtifact("local")
+			}
+			r.AddQualityData(fmt.Sprint("q", i), NewQualityData("1"))
+			r.OutputSnapshot()
+			if i%5 == 0 {
+				r.WriteOutput()
+			}
+		}(i)
+	}
+	wg.Wait()
+
+	output := r.OutputSnapshot()
+	if len(output.Data) != 21 || len(output.QualityData) != 20 || len(r.GetArtifactOutput("a").Value) != 20 {
+		t.Errorf("got %d outputs, %d quality data, %d artifacts", len(output.Data), len(output.QualityData), len(r.GetArtifactOutput("a").Value))
+	}
+}
@@ -0,0 +1,109 @@
+package api
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"os/signal"
+	"runtime/debug"
+	"syscall"
+	"time"
+
+	"github.com/ci-plugins/golang-plugin-sdk/log"
+)
+
+// Context 插件执行上下文，构建被取消时 Done 会被关闭
+type Context struct {
+	context.Context
+	*Runtime
+}
+
+// DefaultGracePeriod 构建被取消后默认的等待时间
+const DefaultGracePeriod = 10 * time.Second
+
+// ErrCancelled 构建被取消
+var ErrCancelled = NewAtomError(StatusFailure, UserError, ErrorCodeCancelled, "build cancelled")
+
+// HandlerFunc 插件执行逻辑
+type HandlerFunc func(ctx *Context) error
+
+// panicError 插件执行逻辑中发生的 panic
+type panicError struct {
+	value interface{}
+}
+
+func (e *panicError) Error() string {
+	return fmt.Sprintf("plugin panic: %v", e.value)
+}
+
+// Run 校验输入后执行插件逻辑并结束构建，返回的错误及 panic 会转换为对应的执行状态
+func (r *Runtime) Run(fn HandlerFunc) {
+	err := r.ValidateInput()
+	if err == nil {
+		err = r.call(fn)
+	}
+	r.FinishBuildFromError(err)
+}
+
+// Run 校验输入后执行插件逻辑并结束构建，返回的错误及 panic 会转换为对应的执行状态
+func Run(fn HandlerFunc) {
+	DefaultRuntime().Run(fn)
+}
+
+// call 执行插件逻辑，收到 SIGTERM 或 SIGINT 时取消上下文，返回 ErrCancelled
+// 宽限期的前一半用于等待插件逻辑返回，剩余时间用于执行清理逻辑
+func (r *Runtime) call(fn HandlerFunc) error {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	signals := make(chan os.Signal, 1)
+	signal.Notify(signals, syscall.SIGTERM, os.Interrupt)
+	defer signal.Stop(signals)
+
+	done := make(chan error, 1)
+	go func() {
+		done <- r.safeCall(ctx, fn)
+	}()
+
+	select {
+	case err := <-done:
+		return err
+	case sig := <-signals:
+		log.Warnf("received signal %s, cancelling build", sig)
+		cancel()
+
+		deadline := time.Now().Add(r.gracePeriod)
+		waitCtx, waitCancel := context.WithTimeout(context.Background(), r.gracePeriod/2)
+		defer waitCancel()
+		select {
+		case <-done:
+		case <-waitCtx.Done():
+			log.Warnf("plugin did not stop within %s", r.gracePeriod/2)
+		}
+
+		cleanupCtx, cleanupCancel := context.WithDeadline(context.Background(), deadline)
+		defer cleanupCancel()
+		runCleanup(cleanupCtx)
+		return ErrCancelled.WithMessage(fmt.Sprintf("build cancelled by signal %s", sig))
+	}
+}
+
+func (r *Runtime) safeCall(ctx context.Context, fn HandlerFunc) (err error) {
+	defer func() {
+		if p := recover(); p != nil {
+			log.Error(string(debug.Stack()))
+			err = PluginErr(ErrorCodeDefault, "").Wrap(&panicError{value: p})
+		}
+	}()
+	return fn(&Context{Context: ctx, Runtime: r})
+}
+
+// SetGracePeriod 设置构建被取消后等待插件逻辑与清理逻辑结束的最长时间
+func (r *Runtime) SetGracePeriod(d time.Duration) {
+	r.gracePeriod = d
+}
+
+// SetGracePeriod 设置构建被取消后等待插件逻辑与清理逻辑结束的最长时间
+func SetGracePeriod(d time.Duration) {
+	DefaultRuntime().SetGracePeriod(d)
+}
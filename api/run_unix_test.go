@@ -0,0 +1,46 @@
+//go:build !windows
+
+package api
+
+import (
+	"context"
+	"errors"
+	"syscall"
+	"testing"
+	"time"
+)
+
+func TestCallCancelRunsCleanupAfterStuckPlugin(t *testing.T) {
+	r := newRuntime(&RuntimeOptions{GracePeriod: 200 * time.Millisecond})
+
+	cleaned := make(chan error, 1)
+	RegisterCleanup(func(ctx context.Context) error {
+		cleaned <- ctx.Err()
+		return nil
+	})
+
+	started := make(chan struct{})
+	release := make(chan struct{})
+	defer close(release)
+	go func() {
+		<-started
+		syscall.Kill(syscall.Getpid(), syscall.SIGTERM)
+	}()
+
+	err := r.call(func(ctx *Context) error {
+		close(started)
+		<-release // 忽略取消
+		return nil
+	})
+	if !errors.Is(err, ErrCancelled) {
+		t.Fatalf("call() error = %v, want ErrCancelled", err)
+	}
+	select {
+	case ctxErr := <-cleaned:
+		if ctxErr != nil {
+			t.Errorf("cleanup context already done: %v", ctxErr)
+		}
+	default:
+		t.Errorf("cleanup was not run")
+	}
+}
@@ -0,0 +1,309 @@
+package api
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/ci-plugins/golang-plugin-sdk/log"
+	"github.com/ci-plugins/golang-plugin-sdk/task"
+)
+
+// NoPostAction 未指定后置动作时 -postAction 的默认值
+const NoPostAction = "noPostAction"
+
+var postActionFlag = flag.String("postAction", NoPostAction, "后置动作")
+
+// TaskFileEnv 插件 task.json 路径的环境变量，未设置时使用可执行文件所在目录下的 task.json
+const TaskFileEnv = "BK_CI_TASK_FILE"
+
+var (
+	gRuntime     *Runtime
+	gRuntimeLock sync.Mutex
+)
+
+// RuntimeOptions 插件运行时选项，未设置的字段使用环境变量或默认值
+type RuntimeOptions struct {
+	DataDir    string // 数据目录，默认取环境变量 bk_data_dir，再默认为当前目录
+	InputFile  string // 输入文件名，默认取环境变量 bk_data_input，再默认为 input.json
+	OutputFile string // 输出文件名，默认取环境变量 bk_data_output，再默认为 output.json
+	PostAction string // 后置动作，默认为 NoPostAction
+	TaskFile   string // 插件的 task.json 路径，设置后用于校验输入与输出，默认取环境变量 BK_CI_TASK_FILE，再默认为可执行文件旁的 task.json
+
+	Local       bool   // 本地运行模式，允许缺少 .sdk.json 与输入文件，默认取 -bk-local 参数或环境变量 BK_CI_LOCAL
+	LocalConfig string // 本地运行模式的配置文件，默认取环境变量 BK_CI_LOCAL_CONFIG
+
+	NamespaceOutputs bool            // 由 SDK 为输出名称加上 namespace 输入的前缀，默认由 worker 添加
+	OutputCheck      OutputCheckMode // 写入输出前按 task.json 校验输出的方式，为 OutputCheckOff 时取环境变量 BK_CI_OUTPUT_CHECK
+	GracePeriod      time.Duration   // 构建被取消后的等待时间，默认为 DefaultGracePeriod
+	Exiter           Exiter          // 结束构建时的退出处理，默认使用 SetExiter 设置的退出处理
+}
+
+// Runtime 插件运行时，持有运行环境、输入参数与插件输出
+type Runtime struct {
+	SdkEnv        *SdkEnv
+	AtomBaseParam *AtomBaseParam
+	AllAtomParam  map[string]interface{}
+	AtomOutput    *AtomOutput // 并发设置输出时应使用 AddOutputData 等函数，读取使用 OutputSnapshot
+
+	dataDir    string
+	inputFile  string
+	outputFile string
+	postAction string
+	taskFile   string
+	task       *task.Task
+
+	outputCheck      OutputCheckMode
+	namespaceOutputs bool
+
+	local       bool
+	localConfig string
+	transport   http.RoundTripper // 本地运行模式未配置网关时使用的 transport
+
+	postEntryParam string
+	mainHandler    HandlerFunc
+	postHandlers   map[string]HandlerFunc
+	gracePeriod    time.Duration
+
+	stateLock  sync.Mutex
+	outputLock sync.RWMutex // 保护 AtomOutput，插件可在多个 goroutine 中设置输出
+	writeLock  sync.Mutex   // 保证同一时间只有一次输出文件写入
+
+	finishLock sync.Mutex
+	finished   bool
+	exiter     Exiter
+
+	checkpointLock     sync.Mutex
+	checkpointEnabled  bool
+	checkpointInterval time.Duration
+	checkpointDirty    bool
+	checkpointStop     chan struct{}
+}
+
+// NewRuntime 创建插件运行时，读取数据目录下的 .sdk.json 与输入文件
+func NewRuntime(opts *RuntimeOptions) (*Runtime, error) {
+	r := newRuntime(opts)
+	if err := r.load(); err != nil {
+		return nil, err
+	}
+	return r, nil
+}
+
+// Load 使用环境变量与命令行参数创建插件运行时
+func Load() (*Runtime, error) {
+	return NewRuntime(defaultRuntimeOptions())
+}
+
+// SetDefaultRuntime 替换包级函数使用的默认运行时，传入 nil 时下次使用会重新加载
+func SetDefaultRuntime(r *Runtime) {
+	gRuntimeLock.Lock()
+	defer gRuntimeLock.Unlock()
+	gRuntime = r
+}
+
+// DefaultRuntime 获取默认运行时，首次调用时加载，加载失败直接结束构建
+func DefaultRuntime() *Runtime {
+	gRuntimeLock.Lock()
+	if gRuntime != nil {
+		r := gRuntime
+		gRuntimeLock.Unlock()
+		return r
+	}
+	r := newRuntime(defaultRuntimeOptions())
+	err := r.load()
+	gRuntime = r
+	gRuntimeLock.Unlock()
+
+	if err != nil {
+		r.FinishBuildFromError(err)
+	}
+	return r
+}
+
+func defaultRuntimeOptions() *RuntimeOptions {
+	if !flag.Parsed() {
+		flag.Parse()
+	}
+	return &RuntimeOptions{
+		PostAction: *postActionFlag,
+	}
+}
+
+func newRuntime(opts *RuntimeOptions) *Runtime {
+	if opts == nil {
+		opts = new(RuntimeOptions)
+	}
+	r := &Runtime{
+		SdkEnv:           new(SdkEnv),
+		AtomBaseParam:    new(AtomBaseParam),
+		AllAtomParam:     make(map[string]interface{}),
+		AtomOutput:       NewAtomOutput(),
+		dataDir:          opts.DataDir,
+		inputFile:        opts.InputFile,
+		outputFile:       opts.OutputFile,
+		postAction:       opts.PostAction,
+		taskFile:         opts.TaskFile,
+		local:            opts.Local,
+		localConfig:      opts.LocalConfig,
+		outputCheck:      opts.OutputCheck,
+		namespaceOutputs: opts.NamespaceOutputs,
+		gracePeriod:      opts.GracePeriod,
+		exiter:           opts.Exiter,
+	}
+	if r.dataDir == "" {
+		r.dataDir = getDataDir()
+	}
+	if r.inputFile == "" {
+		r.inputFile = getInputFile()
+	}
+	if r.outputFile == "" {
+		r.outputFile = getOutputFile()
+	}
+	if r.gracePeriod <= 0 {
+		r.gracePeriod = DefaultGracePeriod
+	}
+	if r.postAction == "" {
+		r.postAction = NoPostAction
+	}
+	if r.taskFile == "" {
+		r.taskFile = defaultTaskFile()
+	}
+	if !r.local {
+		r.local = localEnabled()
+	}
+	if r.localConfig == "" {
+		r.localConfig = strings.TrimSpace(os.Getenv(LocalConfigEnv))
+	}
+	if r.outputCheck == OutputCheckOff {
+		r.outputCheck = outputCheckFromEnv()
+	}
+	r.AtomBaseParam.PostActionParam = r.postAction
+	return r
+}
+
+func (r *Runtime) load() error {
+	if r.local {
+		if err := r.loadLocal(); err != nil {
+			return err
+		}
+		return r.loadTask()
+	}
+	if err := r.loadSdkEnv(); err != nil {
+		return err
+	}
+	if err := r.loadAtomParam(); err != nil {
+		return err
+	}
+	return r.loadTask()
+}
+
+func (r *Runtime) loadSdkEnv() error {
+	data, err := ioutil.ReadFile(filepath.Join(r.dataDir, ".sdk.json"))
+	if err != nil {
+		log.Error("read .sdk.json failed: ", err.Error())
+		return PluginErr(ErrorCodeInitFailed, "read .sdk.json failed")
+	}
+
+	sdkEnv := new(SdkEnv)
+	err = json.Unmarshal(data, sdkEnv)
+	if err != nil {
+		log.Error("parse .sdk.json failed: ", err.Error())
+		return PluginErr(ErrorCodeInitFailed, "read .sdk.json failed")
+	}
+	r.SdkEnv = sdkEnv
+	return nil
+}
+
+func (r *Runtime) loadAtomParam() error {
+	err := r.LoadInputParam(&r.AllAtomParam)
+	if err != nil {
+		log.Error("init atom base param failed: ", err.Error())
+		return PluginErr(ErrorCodeInitFailed, "init atom base param failed")
+	}
+
+	baseParam := new(AtomBaseParam)
+	err = r.LoadInputParam(baseParam)
+	if err != nil {
+		log.Error("init atom base param failed: ", err.Error())
+		return PluginErr(ErrorCodeInitFailed, "init atom base param failed")
+	}
+	r.postEntryParam = baseParam.PostActionParam
+	baseParam.PostActionParam = r.postAction
+	r.AtomBaseParam = baseParam
+	return nil
+}
+
+func (r *Runtime) loadTask() error {
+	if r.taskFile == "" {
+		return nil
+	}
+	t, err := task.LoadFile(r.taskFile)
+	if err != nil {
+		log.Error("load task.json failed: ", err.Error())
+		return PluginErr(ErrorCodeInitFailed, fmt.Sprintf("load task file %s failed", r.taskFile)).Wrap(err)
+	}
+	r.task = t
+	return nil
+}
+
+// defaultTaskFile 环境变量指定的 task.json，未指定时查找可执行文件旁的 task.json，不存在时返回空字符串
+func defaultTaskFile() string {
+	if file := strings.TrimSpace(os.Getenv(TaskFileEnv)); file != "" {
+		return file
+	}
+	exe, err := os.Executable()
+	if err != nil {
+		return ""
+	}
+	file := filepath.Join(filepath.Dir(exe), "task.json")
+	if info, err := os.Stat(file); err != nil || info.IsDir() {
+		return ""
+	}
+	return file
+}
+
+// DataDir 获取数据目录
+func (r *Runtime) DataDir() string {
+	return r.dataDir
+}
+
+// InputFilePath 获取输入文件路径
+func (r *Runtime) InputFilePath() string {
+	return filepath.Join(r.dataDir, r.inputFile)
+}
+
+// OutputFilePath 获取输出文件路径
+func (r *Runtime) OutputFilePath() string {
+	return filepath.Join(r.dataDir, r.outputFile)
+}
+
+func getDataDir() string {
+	dir := strings.TrimSpace(os.Getenv(DataDirEnv))
+	if len(dir) == 0 {
+		dir, _ = os.Getwd()
+	}
+	return dir
+}
+
+func getInputFile() string {
+	file := strings.TrimSpace(os.Getenv(InputFileEnv))
+	if len(file) == 0 {
+		file = "input.json"
+	}
+	return file
+}
+
+func getOutputFile() string {
+	file := strings.TrimSpace(os.Getenv(OutputFileEnv))
+	if len(file) == 0 {
+		file = "output.json"
+	}
+	return file
+}
@@ -0,0 +1,100 @@
+package api
+
+import (
+	"encoding/json"
+	"errors"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+
+	"github.com/ci-plugins/golang-plugin-sdk/log"
+)
+
+// ErrStateNotFound 未找到指定的状态
+var ErrStateNotFound = errors.New("state not found")
+
+const stateDir = ".bk_state"
+
+// StateFilePath 获取状态文件路径，同一插件任务的主流程与后置动作共用该文件
+func (r *Runtime) StateFilePath() string {
+	taskId := r.AtomBaseParam.TaskId
+	if taskId == "" {
+		taskId = r.SdkEnv.TaskId
+	}
+	if taskId == "" {
+		taskId = "default"
+	}
+	return filepath.Join(r.dataDir, stateDir, taskId+".json")
+}
+
+// SaveState 保存状态，供后置动作通过 GetState 读取
+func (r *Runtime) SaveState(key string, value string) error {
+	r.stateLock.Lock()
+	defer r.stateLock.Unlock()
+
+	states, err := r.readStates()
+	if err != nil {
+		return err
+	}
+	states[key] = value
+
+	data, _ := json.Marshal(states)
+	file := r.StateFilePath()
+	if err = os.MkdirAll(filepath.Dir(file), 0755); err != nil {
+		log.Error("create state dir failed: ", err.Error())
+		return errors.New("save state failed")
+	}
+	tmp := file + ".tmp"
+	if err = ioutil.WriteFile(tmp, data, 0600); err != nil {
+		log.Error("write state failed: ", err.Error())
+		return errors.New("save state failed")
+	}
+	if err = os.Rename(tmp, file); err != nil {
+		log.Error("write state failed: ", err.Error())
+		return errors.New("save state failed")
+	}
+	return nil
+}
+
+// SaveState 保存状态，供后置动作通过 GetState 读取
+func SaveState(key string, value string) error {
+	return DefaultRuntime().SaveState(key, value)
+}
+
+// GetState 获取主流程保存的状态，不存在时返回 ErrStateNotFound
+func (r *Runtime) GetState(key string) (string, error) {
+	r.stateLock.Lock()
+	defer r.stateLock.Unlock()
+
+	states, err := r.readStates()
+	if err != nil {
+		return "", err
+	}
+	value, ok := states[key]
+	if !ok {
+		return "", ErrStateNotFound
+	}
+	return value, nil
+}
+
+// GetState 获取主流程保存的状态，不存在时返回 ErrStateNotFound
+func GetState(key string) (string, error) {
+	return DefaultRuntime().GetState(key)
+}
+
+func (r *Runtime) readStates() (map[string]string, error) {
+	states := make(map[string]string)
+	data, err := ioutil.ReadFile(r.StateFilePath())
+	if os.IsNotExist(err) {
+		return states, nil
+	}
+	if err != nil {
+		log.Error("read state failed: ", err.Error())
+		return nil, errors.New("read state failed")
+	}
+	if err = json.Unmarshal(data, &states); err != nil {
+		log.Error("parse state failed: ", err.Error())
+		return nil, errors.New("parse state failed")
+	}
+	return states, nil
+}
@@ -0,0 +1,64 @@
+package api
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestStateMainToPost(t *testing.T) {
+	dir := t.TempDir()
+	main := newRuntime(&RuntimeOptions{DataDir: dir})
+	main.AtomBaseParam.TaskId = "e-1"
+	if err := main.SaveState("pid", "42"); err != nil {
+		t.Fatalf("SaveState() error = %v", err)
+	}
+	if err := main.SaveState("workspace", "/tmp/ws"); err != nil {
+		t.Fatalf("SaveState() error = %v", err)
+	}
+	if err := main.SaveState("pid", "43"); err != nil {
+		t.Fatalf("SaveState() error = %v", err)
+	}
+
+	post := newRuntime(&RuntimeOptions{DataDir: dir, PostAction: "cleanup"})
+	post.AtomBaseParam.TaskId = "e-1"
+	for key, want := range map[string]string{"pid": "43", "workspace": "/tmp/ws"} {
+		if got, err := post.GetState(key); err != nil || got != want {
+			t.Errorf("GetState(%s) = %q, %v, want %q", key, got, err, want)
+		}
+	}
+	if _, err := post.GetState("missing"); err != ErrStateNotFound {
+		t.Errorf("GetState(missing) error = %v, want ErrStateNotFound", err)
+	}
+
+	// 其他任务的状态互不影响
+	other := newRuntime(&RuntimeOptions{DataDir: dir})
+	other.AtomBaseParam.TaskId = "e-2"
+	if _, err := other.GetState("pid"); err != ErrStateNotFound {
+		t.Errorf("GetState() of another task error = %v, want ErrStateNotFound", err)
+	}
+	if info, err := os.Stat(main.StateFilePath()); err != nil || info.Mode().Perm() != 0600 {
+		t.Errorf("state file mode = %v, %v", info, err)
+	}
+}
+
+func TestStateCorrupt(t *testing.T) {
+	r := newRuntime(&RuntimeOptions{DataDir: t.TempDir()})
+	r.SdkEnv.TaskId = "e-1"
+	if got := filepath.Base(r.StateFilePath()); got != "e-1.json" {
+		t.Errorf("StateFilePath() = %s, want task id from .sdk.json", got)
+	}
+	if err := os.MkdirAll(filepath.Dir(r.StateFilePath()), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(r.StateFilePath(), []byte("{not json"), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := r.GetState("pid"); err == nil || err == ErrStateNotFound {
+		t.Errorf("GetState() with corrupt file error = %v, want parse error", err)
+	}
+	if err := r.SaveState("pid", "1"); err == nil {
+		t.Errorf("SaveState() with corrupt file error = nil, it should not drop existing state")
+	}
+}
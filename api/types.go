@@ -129,6 +129,16 @@ const (
 	PluginError     ErrorType = 3
 )
 
+// SDK 内置错误码
+const (
+	ErrorCodeInitFailed    = 2189503 // 插件运行时初始化失败
+	ErrorCodeDefault       = 2199001 // 插件执行失败的默认错误码
+	ErrorCodeInputInvalid  = 2199002 // 输入参数不合法
+	ErrorCodeOutputInvalid = 2199003 // 插件输出与 task.json 声明不一致
+	ErrorCodeNotFinished   = 2199004 // 插件未正常结束，输出为检查点
+	ErrorCodeCancelled     = 2199005 // 构建被取消
+)
+
 // ReportType 报告类型
 type ReportType string
 
@@ -260,6 +270,50 @@ type AtomOutput struct {
 	PlatformErrorCode int                     `json:"platformErrorCode"`
 }
 
+// clone 复制插件输出，SDK 定义的输出数据会一并复制
+func (o *AtomOutput) clone() *AtomOutput {
+	c := *o
+	c.Data = make(map[string]interface{}, len(o.Data))
+	for key, data := range o.Data {
+		c.Data[key] = cloneOutputData(data)
+	}
+	c.QualityData = make(map[string]*Qualitydata, len(o.QualityData))
+	for key, data := range o.QualityData {
+		if data != nil {
+			d := *data
+			data = &d
+		}
+		c.QualityData[key] = data
+	}
+	return &c
+}
+
+// cloneOutputData 复制 SDK 定义的输出数据，其他类型原样返回
+func cloneOutputData(data interface{}) interface{} {
+	switch v := data.(type) {
+	case *StringData:
+		if v == nil {
+			return v
+		}
+		d := *v
+		return &d
+	case *ArtifactData:
+		if v == nil {
+			return v
+		}
+		d := *v
+		d.Value = append([]string{}, v.Value...)
+		return &d
+	case *ReportData:
+		if v == nil {
+			return v
+		}
+		d := *v
+		return &d
+	}
+	return data
+}
+
 // NewAtomOutput 创建插件输出
 func NewAtomOutput() *AtomOutput {
 	output := new(AtomOutput)
@@ -0,0 +1,328 @@
+package api
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/ci-plugins/golang-plugin-sdk/log"
+	"github.com/ci-plugins/golang-plugin-sdk/task"
+)
+
+var (
+	alphaDashRegexp  = regexp.MustCompile(`^[\p{L}\p{N}_-]*$`)
+	outputNameRegexp = regexp.MustCompile(`^[a-zA-Z_][a-zA-Z0-9_]*$`)
+)
+
+// OutputCheckEnv 设置输出校验方式的环境变量，取值为 off、warn、strict
+const OutputCheckEnv = "BK_CI_OUTPUT_CHECK"
+
+// OutputCheckMode 写入输出前按 task.json 校验输出的方式
+type OutputCheckMode int
+
+// 输出校验方式
+const (
+	OutputCheckOff    OutputCheckMode = iota // 不校验
+	OutputCheckWarn                          // 校验失败时打印警告
+	OutputCheckStrict                        // 校验失败时结束构建为失败
+)
+
+// ValidationError 校验失败的全部问题
+type ValidationError struct {
+	Problems []string
+}
+
+// Error 错误信息
+func (e *ValidationError) Error() string {
+	return strings.Join(e.Problems, "; ")
+}
+
+func (e *ValidationError) addf(format string, v ...interface{}) {
+	e.Problems = append(e.Problems, fmt.Sprintf(format, v...))
+}
+
+// SetTask 设置插件的 task.json 配置，设置后 Run 会在执行插件逻辑前校验输入
+func (r *Runtime) SetTask(t *task.Task) {
+	r.task = t
+}
+
+// SetTask 设置插件的 task.json 配置，设置后 Run 会在执行插件逻辑前校验输入
+func SetTask(t *task.Task) {
+	DefaultRuntime().SetTask(t)
+}
+
+// GetTask 获取插件的 task.json 配置，未设置时返回 nil
+func (r *Runtime) GetTask() *task.Task {
+	return r.task
+}
+
+// GetTask 获取插件的 task.json 配置，未设置时返回 nil
+func GetTask() *task.Task {
+	return DefaultRuntime().GetTask()
+}
+
+// ValidateInput 按 task.json 声明校验输入参数，存在问题时返回包含全部问题的用户错误
+func (r *Runtime) ValidateInput() error {
+	if r.task == nil {
+		return nil
+	}
+
+	verr := new(ValidationError)
+	for _, input := range r.task.Input {
+		if !input.Rely.Satisfied(r.AllAtomParam) {
+			continue
+		}
+		validateInput(verr, input, r.AllAtomParam[input.Name])
+	}
+	if len(verr.Problems) == 0 {
+		return nil
+	}
+	return UserErr(ErrorCodeInputInvalid, "invalid input").Wrap(verr)
+}
+
+// ValidateInput 按 task.json 声明校验输入参数，存在问题时返回包含全部问题的用户错误
+func ValidateInput() error {
+	return DefaultRuntime().ValidateInput()
+}
+
+// SetOutputCheck 设置写入输出前按 task.json 校验输出的方式，需同时设置 task.json
+func (r *Runtime) SetOutputCheck(mode OutputCheckMode) {
+	r.outputCheck = mode
+}
+
+// SetOutputCheck 设置写入输出前按 task.json 校验输出的方式，需同时设置 task.json
+func SetOutputCheck(mode OutputCheckMode) {
+	DefaultRuntime().SetOutputCheck(mode)
+}
+
+// ValidateOutput 按 task.json 声明校验输出的名称与类型，存在问题时返回包含全部问题的插件错误
+func (r *Runtime) ValidateOutput() error {
+	if r.task == nil {
+		return nil
+	}
+
+	output := r.OutputSnapshot()
+	verr := new(ValidationError)
+	keys := make([]string, 0, len(output.Data))
+	for key := range output.Data {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	for _, key := range keys {
+		if !outputNameRegexp.MatchString(key) {
+			verr.addf("output %s: name may only contain letters, numbers and underscores and must not start with a number", key)
+		}
+		declared := r.task.Output.Get(r.outputName(key))
+		if declared == nil {
+			verr.addf("output %s is not declared in task.json", key)
+			continue
+		}
+		declaredType := declared.DataType()
+		actual := outputDataType(output.Data[key])
+		if actual == "" {
+			verr.addf("output %s: unknown data type %T", key, output.Data[key])
+		} else if actual != declaredType {
+			verr.addf("output %s is declared as %s but got %s", key, declaredType, actual)
+		}
+	}
+	if len(verr.Problems) == 0 {
+		return nil
+	}
+	return PluginErr(ErrorCodeOutputInvalid, "invalid output").Wrap(verr)
+}
+
+// ValidateOutput 按 task.json 声明校验输出的名称与类型，存在问题时返回包含全部问题的插件错误
+func ValidateOutput() error {
+	return DefaultRuntime().ValidateOutput()
+}
+
+// checkOutput 按设置的校验方式校验输出，严格模式下将执行状态改为失败
+func (r *Runtime) checkOutput() error {
+	if r.outputCheck == OutputCheckOff {
+		return nil
+	}
+	if r.task == nil {
+		log.Warn("output check is enabled but task.json is not loaded, set " + TaskFileEnv + " or call SetTask")
+		return nil
+	}
+	err := r.ValidateOutput()
+	if err == nil {
+		return nil
+	}
+	if r.outputCheck == OutputCheckWarn {
+		log.Warn(err.Error())
+		return nil
+	}
+
+	log.Error(err.Error())
+	aerr := err.(*AtomError)
+	r.updateOutput(func(output *AtomOutput) {
+		if output.Status == StatusSuccess {
+			output.Status = aerr.Status
+			output.ErrorCode = aerr.ErrorCode
+			output.ErrorType = aerr.ErrorType
+			output.Message = aerr.Error()
+		}
+	})
+	return err
+}
+
+// outputDataType 输出数据对应的 task.json 输出类型，无法识别时返回空字符串
+func outputDataType(data interface{}) string {
+	switch v := data.(type) {
+	case *StringData, StringData:
+		return task.OutputTypeString
+	case *ArtifactData, ArtifactData:
+		return task.OutputTypeArtifact
+	case *ReportData, ReportData:
+		return task.OutputTypeReport
+	case map[string]interface{}:
+		t, _ := v["type"].(string)
+		return t
+	}
+	return ""
+}
+
+func outputCheckFromEnv() OutputCheckMode {
+	switch strings.ToLower(strings.TrimSpace(os.Getenv(OutputCheckEnv))) {
+	case "warn":
+		return OutputCheckWarn
+	case "strict":
+		return OutputCheckStrict
+	default:
+		return OutputCheckOff
+	}
+}
+
+func validateInput(verr *ValidationError, input *task.Input, value interface{}) {
+	values := inputValues(input, value)
+	if len(values) == 0 {
+		if input.Required {
+			verr.addf("input %s is required", inputTitle(input))
+		}
+		return
+	}
+
+	switch input.Type {
+	case task.TypeSelector, task.TypeDevopsSelect, task.TypeEnumInput, task.TypeCheckboxList:
+		choices := input.Choices()
+		if len(choices) == 0 {
+			// 选项由接口动态获取时无法校验
+			break
+		}
+		if len(values) > 1 && !input.MultiSelect && input.Type != task.TypeCheckboxList {
+			verr.addf("input %s accepts only one value", inputTitle(input))
+		}
+		for _, v := range values {
+			if !hasChoice(choices, v) {
+				verr.addf("input %s: %q is not a valid option", inputTitle(input), v)
+			}
+		}
+	case task.TypeCheckbox:
+		if _, err := strconv.ParseBool(values[0]); err != nil {
+			verr.addf("input %s: %q is not a boolean", inputTitle(input), values[0])
+		}
+	}
+
+	if input.Rule != nil {
+		for _, v := range values {
+			validateRule(verr, input, v)
+		}
+	}
+}
+
+func validateRule(verr *ValidationError, input *task.Input, value string) {
+	rule := input.Rule
+	length := utf8.RuneCountInString(value)
+	if rule.Min > 0 && length < rule.Min {
+		verr.addf("input %s must be at least %d characters", inputTitle(input), rule.Min)
+	}
+	if rule.Max > 0 && length > rule.Max {
+		verr.addf("input %s must be at most %d characters", inputTitle(input), rule.Max)
+	}
+	if rule.Numeric {
+		if _, err := strconv.ParseFloat(value, 64); err != nil {
+			verr.addf("input %s must be numeric", inputTitle(input))
+		}
+	}
+	if rule.AlphaDash && !alphaDashRegexp.MatchString(value) {
+		verr.addf("input %s may only contain letters, numbers, dashes and underscores", inputTitle(input))
+	}
+	if rule.Regex != "" {
+		re, err := regexp.Compile(rule.Regex)
+		if err != nil {
+			verr.addf("input %s: invalid rule regex %q: %s", inputTitle(input), rule.Regex, err.Error())
+		} else if !re.MatchString(value) {
+			verr.addf("input %s does not match %s", inputTitle(input), rule.Regex)
+		}
+	}
+}
+
+func inputTitle(input *task.Input) string {
+	if input.Label == "" {
+		return input.Name
+	}
+	return fmt.Sprintf("%s(%s)", input.Name, input.Label)
+}
+
+func hasChoice(choices []*task.Option, value string) bool {
+	for _, c := range choices {
+		if c.Key() == value {
+			return true
+		}
+	}
+	return false
+}
+
+// inputValues 将输入值展开为字符串列表，多选类组件的值可能是数组、JSON 数组字符串或逗号分隔的字符串
+func inputValues(input *task.Input, value interface{}) []string {
+	var values []string
+	switch v := value.(type) {
+	case nil:
+		return nil
+	case []interface{}:
+		for _, item := range v {
+			values = append(values, stringValue(item))
+		}
+	case string:
+		if strings.TrimSpace(v) == "" {
+			return nil
+		}
+		if !input.MultiSelect && input.Type != task.TypeCheckboxList {
+			return []string{v}
+		}
+		var list []interface{}
+		if err := json.Unmarshal([]byte(v), &list); err == nil {
+			return inputValues(input, list)
+		}
+		for _, item := range strings.Split(v, ",") {
+			values = append(values, strings.TrimSpace(item))
+		}
+	default:
+		values = append(values, stringValue(v))
+	}
+	return values
+}
+
+// stringValue 将输入值转换为字符串，对象与数组转换为 JSON
+func stringValue(value interface{}) string {
+	switch v := value.(type) {
+	case nil:
+		return ""
+	case string:
+		return v
+	case bool, float64, json.Number:
+		return fmt.Sprint(v)
+	default:
+		data, err := json.Marshal(v)
+		if err != nil {
+			return fmt.Sprint(v)
+		}
+		return string(data)
+	}
+}
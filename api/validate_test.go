@@ -0,0 +1,232 @@
+package api
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/ci-plugins/golang-plugin-sdk/task"
+)
+
+const validateTaskJSON = `{
+	"atomCode": "demo",
+	"input": {
+		"name": {"label": "名称", "type": "vuex-input", "required": true, "rule": {"min": 2, "max": 5, "alpha_dash": true}},
+		"count": {"type": "vuex-input", "rule": {"numeric": true}},
+		"mode": {"type": "selector", "options": [{"id": "fast", "name": "Fast"}, {"id": "slow", "name": "Slow"}]},
+		"langs": {"type": "atom-checkbox-list", "list": [{"id": "go", "name": "Go"}, {"id": "java", "name": "Java"}]},
+		"enabled": {"type": "atom-checkbox"},
+		"script": {"type": "atom-ace-editor", "required": true, "rely": {"operation": "AND", "expression": [{"key": "mode", "value": "slow"}]}},
+		"version": {"type": "vuex-input", "rule": {"regex": "^v[0-9]+$"}}
+	},
+	"output": {
+		"ver": {"type": "string"},
+		"pkg": {"type": "artifact"},
+		"report": {"type": "report"},
+		"plain": {}
+	}
+}`
+
+func validateTask(t *testing.T) *task.Task {
+	t.Helper()
+	tk, err := task.Parse([]byte(validateTaskJSON))
+	if err != nil {
+		t.Fatalf("parse task: %v", err)
+	}
+	return tk
+}
+
+func TestValidateInput(t *testing.T) {
+	tests := []struct {
+		name     string
+		params   map[string]interface{}
+		problems []string
+	}{
+		{"valid", map[string]interface{}{"name": "ab_c", "count": "12", "mode": "fast", "langs": `["go"]`, "enabled": "true", "version": "v1"}, nil},
+		{"required", map[string]interface{}{"name": " "}, []string{"input name(名称) is required"}},
+		{"rule", map[string]interface{}{"name": "a b c d e f", "count": "x", "version": "1"}, []string{
+			"input name(名称) must be at most 5 characters",
+			"input name(名称) may only contain letters, numbers, dashes and underscores",
+			"input count must be numeric",
+			"input version does not match ^v[0-9]+$",
+		}},
+		{"options", map[string]interface{}{"name": "ab", "mode": "medium", "langs": "go,rust", "enabled": "maybe"}, []string{
+			`input mode: "medium" is not a valid option`,
+			`input langs: "rust" is not a valid option`,
+			`input enabled: "maybe" is not a boolean`,
+		}},
+		{"rely satisfied", map[string]interface{}{"name": "ab", "mode": "slow"}, []string{"input script is required"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := newRuntime(nil)
+			r.AllAtomParam = tt.params
+			r.SetTask(validateTask(t))
+
+			err := r.ValidateInput()
+			if len(tt.problems) == 0 {
+				if err != nil {
+					t.Fatalf("ValidateInput() error = %v", err)
+				}
+				return
+			}
+			var verr *ValidationError
+			if !errors.As(err, &verr) {
+				t.Fatalf("ValidateInput() error = %v, want ValidationError", err)
+			}
+			if strings.Join(verr.Problems, "\n") != strings.Join(tt.problems, "\n") {
+				t.Errorf("problems = %q, want %q", verr.Problems, tt.problems)
+			}
+		})
+	}
+}
+
+func TestValidateInputWithoutTask(t *testing.T) {
+	r := newRuntime(nil)
+	if err := r.ValidateInput(); err != nil {
+		t.Errorf("ValidateInput() without task error = %v", err)
+	}
+}
+
+func TestLoadTask(t *testing.T) {
+	dir := t.TempDir()
+	file := filepath.Join(dir, "task.json")
+	if err := os.WriteFile(file, []byte(validateTaskJSON), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	r := newRuntime(&RuntimeOptions{TaskFile: file})
+	if err := r.loadTask(); err != nil || r.GetTask() == nil || r.GetTask().AtomCode != "demo" {
+		t.Errorf("loadTask() = %v, task %v", err, r.GetTask())
+	}
+
+	r = newRuntime(&RuntimeOptions{TaskFile: filepath.Join(dir, "missing.json")})
+	err := r.loadTask()
+	var aerr *AtomError
+	if !errors.As(err, &aerr) || aerr.ErrorCode != ErrorCodeInitFailed || !strings.Contains(err.Error(), "missing.json") {
+		t.Errorf("loadTask() missing file error = %v, want init failed naming the file", err)
+	}
+}
+
+func TestDefaultTaskFile(t *testing.T) {
+	t.Setenv(TaskFileEnv, "/path/to/task.json")
+	if got := defaultTaskFile(); got != "/path/to/task.json" {
+		t.Errorf("defaultTaskFile() = %q, want env value", got)
+	}
+
+	// 未设置 TaskFile 的运行时同样使用环境变量
+	if r := newRuntime(&RuntimeOptions{}); r.taskFile != "/path/to/task.json" {
+		t.Errorf("newRuntime() taskFile = %q, want env value", r.taskFile)
+	}
+	if r := newRuntime(&RuntimeOptions{TaskFile: "own.json"}); r.taskFile != "own.json" {
+		t.Errorf("newRuntime() taskFile = %q, want own.json", r.taskFile)
+	}
+
+	// 测试二进制旁没有 task.json
+	t.Setenv(TaskFileEnv, "")
+	if got := defaultTaskFile(); got != "" {
+		t.Errorf("defaultTaskFile() = %q, want empty", got)
+	}
+}
+
+func TestValidateOutput(t *testing.T) {
+	tests := []struct {
+		name     string
+		data     map[string]interface{}
+		problems []string
+	}{
+		{"valid", map[string]interface{}{
+			"ver":    NewStringData("1"),
+			"pkg":    NewArtifactData(),
+			"report": NewThirdpartyReportData("r", "http://example.com"),
+			"plain":  NewStringData("x"),
+		}, nil},
+		{"undeclared", map[string]interface{}{"extra": NewStringData("1")}, []string{"output extra is not declared in task.json"}},
+		{"bad name", map[string]interface{}{"1ver": NewStringData("1")}, []string{
+			"output 1ver: name may only contain letters, numbers and underscores and must not start with a number",
+			"output 1ver is not declared in task.json",
+		}},
+		{"type mismatch", map[string]interface{}{"pkg": NewStringData("a"), "ver": map[string]interface{}{"type": "report"}}, []string{
+			"output pkg is declared as artifact but got string",
+			"output ver is declared as string but got report",
+		}},
+		{"unknown type", map[string]interface{}{"ver": "raw"}, []string{"output ver: unknown data type string"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := newRuntime(nil)
+			r.SetTask(validateTask(t))
+			r.AtomOutput.Data = tt.data
+
+			err := r.ValidateOutput()
+			if len(tt.problems) == 0 {
+				if err != nil {
+					t.Fatalf("ValidateOutput() error = %v", err)
+				}
+				return
+			}
+			var verr *ValidationError
+			if !errors.As(err, &verr) {
+				t.Fatalf("ValidateOutput() error = %v, want ValidationError", err)
+			}
+			if strings.Join(verr.Problems, "\n") != strings.Join(tt.problems, "\n") {
+				t.Errorf("problems = %q, want %q", verr.Problems, tt.problems)
+			}
+		})
+	}
+}
+
+func TestWriteOutputCheck(t *testing.T) {
+	tests := []struct {
+		name   string
+		mode   OutputCheckMode
+		task   bool
+		status Status
+		err    bool
+	}{
+		{"off", OutputCheckOff, true, StatusSuccess, false},
+		{"warn", OutputCheckWarn, true, StatusSuccess, false},
+		{"strict", OutputCheckStrict, true, StatusError, true},
+		{"strict without task", OutputCheckStrict, false, StatusSuccess, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := newRuntime(&RuntimeOptions{DataDir: t.TempDir(), OutputCheck: tt.mode})
+			if tt.task {
+				r.SetTask(validateTask(t))
+			}
+			r.AddOutputData("extra", NewStringData("1"))
+
+			err := r.WriteOutput()
+			if (err != nil) != tt.err {
+				t.Errorf("WriteOutput() error = %v, want error %v", err, tt.err)
+			}
+			output := r.OutputSnapshot()
+			if output.Status != tt.status {
+				t.Errorf("status = %s, want %s", output.Status, tt.status)
+			}
+			if tt.err && output.ErrorCode != ErrorCodeOutputInvalid {
+				t.Errorf("error code = %d, want %d", output.ErrorCode, ErrorCodeOutputInvalid)
+			}
+		})
+	}
+}
+
+func TestOutputCheckFromEnv(t *testing.T) {
+	for value, want := range map[string]OutputCheckMode{"": OutputCheckOff, "warn": OutputCheckWarn, " Strict ": OutputCheckStrict, "bogus": OutputCheckOff} {
+		t.Setenv(OutputCheckEnv, value)
+		if got := outputCheckFromEnv(); got != want {
+			t.Errorf("outputCheckFromEnv(%q) = %d, want %d", value, got, want)
+		}
+		if got := newRuntime(&RuntimeOptions{}).outputCheck; got != want {
+			t.Errorf("newRuntime() outputCheck with %q = %d, want %d", value, got, want)
+		}
+	}
+
+	t.Setenv(OutputCheckEnv, "strict")
+	if got := newRuntime(&RuntimeOptions{OutputCheck: OutputCheckWarn}).outputCheck; got != OutputCheckWarn {
+		t.Errorf("OutputCheck option should take precedence over env, got %d", got)
+	}
+}
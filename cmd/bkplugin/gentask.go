@@ -0,0 +1,564 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"go/ast"
+	"go/parser"
+	"go/token"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strconv"
+	"strings"
+
+	"github.com/ci-plugins/golang-plugin-sdk/api"
+	"github.com/ci-plugins/golang-plugin-sdk/task"
+)
+
+// 由结构体生成、会被覆盖的 task.json 字段，其他字段（如 rely、rule）保留
+var (
+	generatedInputKeys  = []string{"label", "type", "default", "placeholder", "desc", "required", "multiSelect", "options", "list"}
+	generatedOutputKeys = []string{"description", "type", "isSensitive"}
+)
+
+func gentaskCommand(args []string) int {
+	fs := flag.NewFlagSet("gentask", flag.ExitOnError)
+	taskFile := fs.String("task", "task.json", "需要生成或更新的 task.json 路径")
+	src := fs.String("src", ".", "声明输入输出结构体的 go 包所在目录")
+	inputType := fs.String("input", "Input", "输入结构体名称，为空时不更新 input")
+	outputType := fs.String("output", "Output", "输出结构体名称，为空时不更新 output")
+	atomCode := fs.String("atom", "", "创建 task.json 时使用的 atomCode，默认为目录名")
+	fs.Parse(args)
+
+	structs, err := parseStructs(*src)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "parse %s error %s\n", *src, err.Error())
+		return 1
+	}
+
+	data, err := ioutil.ReadFile(*taskFile)
+	if os.IsNotExist(err) {
+		if *atomCode == "" {
+			dir, _ := filepath.Abs(filepath.Dir(*taskFile))
+			*atomCode = filepath.Base(dir)
+		}
+		data, err = json.Marshal(&task.Task{AtomCode: *atomCode, Execution: &task.Execution{Language: "golang"}})
+	}
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "read %s error %s\n", *taskFile, err.Error())
+		return 1
+	}
+	doc, err := parseRawObject(data)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "parse %s error %s\n", *taskFile, err.Error())
+		return 1
+	}
+
+	if *inputType != "" {
+		st, ok := structs[*inputType]
+		if !ok {
+			fmt.Fprintf(os.Stderr, "struct %s not found in %s\n", *inputType, *src)
+			return 1
+		}
+		inputs, err := structInputs(st, structs)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "%s\n", err.Error())
+			return 1
+		}
+		if err = doc.updateSection("input", len(inputs), func(i int) (string, interface{}) {
+			return inputs[i].Name, inputs[i]
+		}, generatedInputKeys); err != nil {
+			fmt.Fprintf(os.Stderr, "update input error %s\n", err.Error())
+			return 1
+		}
+		fmt.Fprintf(os.Stdout, "input: %d fields from %s\n", len(inputs), *inputType)
+	}
+	if *outputType != "" {
+		st, ok := structs[*outputType]
+		if !ok {
+			fmt.Fprintf(os.Stderr, "struct %s not found in %s\n", *outputType, *src)
+			return 1
+		}
+		outputs, err := structOutputs(st, structs)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "%s\n", err.Error())
+			return 1
+		}
+		if err = doc.updateSection("output", len(outputs), func(i int) (string, interface{}) {
+			return outputs[i].Name, outputs[i]
+		}, generatedOutputKeys); err != nil {
+			fmt.Fprintf(os.Stderr, "update output error %s\n", err.Error())
+			return 1
+		}
+		fmt.Fprintf(os.Stdout, "output: %d fields from %s\n", len(outputs), *outputType)
+	}
+
+	out, err := doc.marshalIndent()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "marshal task.json error %s\n", err.Error())
+		return 1
+	}
+	if _, err = task.Parse(out); err != nil {
+		fmt.Fprintf(os.Stderr, "generated task.json is invalid: %s\n", err.Error())
+		return 1
+	}
+	if err = ioutil.WriteFile(*taskFile, out, 0644); err != nil {
+		fmt.Fprintf(os.Stderr, "write %s error %s\n", *taskFile, err.Error())
+		return 1
+	}
+	fmt.Fprintf(os.Stdout, "update %s\n", *taskFile)
+	return 0
+}
+
+// structField 结构体字段及其注释
+type structField struct {
+	name     string
+	typeExpr ast.Expr
+	tag      reflect.StructTag
+	doc      string
+	embedded bool
+}
+
+// parseStructs 解析目录下非测试文件中的全部结构体声明
+func parseStructs(dir string) (map[string][]*structField, error) {
+	fset := token.NewFileSet()
+	pkgs, err := parser.ParseDir(fset, dir, func(info os.FileInfo) bool {
+		return !strings.HasSuffix(info.Name(), "_test.go")
+	}, parser.ParseComments)
+	if err != nil {
+		return nil, err
+	}
+	structs := make(map[string][]*structField)
+	for _, pkg := range pkgs {
+		for _, file := range pkg.Files {
+			ast.Inspect(file, func(n ast.Node) bool {
+				spec, ok := n.(*ast.TypeSpec)
+				if !ok {
+					return true
+				}
+				st, ok := spec.Type.(*ast.StructType)
+				if !ok {
+					return true
+				}
+				structs[spec.Name.Name] = structFields(st)
+				return false
+			})
+		}
+	}
+	return structs, nil
+}
+
+func structFields(st *ast.StructType) []*structField {
+	var fields []*structField
+	for _, f := range st.Fields.List {
+		var tag reflect.StructTag
+		if f.Tag != nil {
+			if s, err := strconv.Unquote(f.Tag.Value); err == nil {
+				tag = reflect.StructTag(s)
+			}
+		}
+		doc := strings.TrimSpace(f.Doc.Text())
+		if doc == "" {
+			doc = strings.TrimSpace(f.Comment.Text())
+		}
+		if len(f.Names) == 0 {
+			fields = append(fields, &structField{name: typeName(f.Type), typeExpr: f.Type, tag: tag, doc: doc, embedded: true})
+			continue
+		}
+		for _, name := range f.Names {
+			if !name.IsExported() {
+				continue
+			}
+			fields = append(fields, &structField{name: name.Name, typeExpr: f.Type, tag: tag, doc: doc})
+		}
+	}
+	return fields
+}
+
+// typeName 类型表达式的名称，去掉指针、切片与包名
+func typeName(expr ast.Expr) string {
+	switch t := expr.(type) {
+	case *ast.Ident:
+		return t.Name
+	case *ast.StarExpr:
+		return typeName(t.X)
+	case *ast.SelectorExpr:
+		return t.Sel.Name
+	case *ast.ArrayType:
+		return typeName(t.Elt)
+	}
+	return ""
+}
+
+// flattenFields 展开同一包内的嵌入结构体
+func flattenFields(fields []*structField, structs map[string][]*structField, depth int) []*structField {
+	var flat []*structField
+	for _, f := range fields {
+		if f.embedded && f.tag.Get(api.BindTag) == "" {
+			if embedded, ok := structs[f.name]; ok && depth < 8 {
+				flat = append(flat, flattenFields(embedded, structs, depth+1)...)
+			}
+			continue
+		}
+		flat = append(flat, f)
+	}
+	return flat
+}
+
+// fieldName 与 Bind 相同的规则获取输入名称，跳过时返回空字符串
+func fieldName(f *structField) (string, []string) {
+	tag, ok := f.tag.Lookup(api.BindTag)
+	if !ok {
+		jsonTag := f.tag.Get("json")
+		if jsonTag == "-" {
+			return "", nil
+		}
+		if name := strings.Split(jsonTag, ",")[0]; name != "" {
+			return name, nil
+		}
+		return f.name, nil
+	}
+	if tag == "-" {
+		return "", nil
+	}
+	parts := strings.Split(tag, ",")
+	name := strings.TrimSpace(parts[0])
+	if name == "" {
+		name = f.name
+	}
+	return name, parts[1:]
+}
+
+// docText 拆分字段注释，第一行去掉字段名后作为标题，其余作为描述
+func docText(f *structField) (string, string) {
+	lines := strings.SplitN(f.doc, "\n", 2)
+	title := strings.TrimSpace(strings.TrimPrefix(lines[0], f.name))
+	var desc string
+	if len(lines) > 1 {
+		desc = strings.TrimSpace(lines[1])
+	}
+	return title, desc
+}
+
+// structInputs 按字段标签生成输入声明
+// 支持的标签：bk（名称、required、default=）、label、desc、placeholder、type、options（id:名称,id:名称）
+func structInputs(fields []*structField, structs map[string][]*structField) (task.Inputs, error) {
+	var inputs task.Inputs
+	for _, f := range flattenFields(fields, structs, 0) {
+		name, opts := fieldName(f)
+		if name == "" {
+			continue
+		}
+		if inputs.Get(name) != nil {
+			return nil, fmt.Errorf("input %s is declared more than once", name)
+		}
+		title, desc := docText(f)
+		input := &task.Input{
+			Name:        name,
+			Label:       tagOr(f.tag, "label", title),
+			Desc:        tagOr(f.tag, "desc", desc),
+			Placeholder: f.tag.Get("placeholder"),
+			Type:        f.tag.Get("type"),
+		}
+		for i, opt := range opts {
+			opt = strings.TrimSpace(opt)
+			if opt == "required" {
+				input.Required = true
+			}
+			if strings.HasPrefix(opt, "default=") {
+				input.Default = strings.TrimPrefix(strings.Join(opts[i:], ","), "default=")
+				break
+			}
+		}
+
+		_, isSlice := f.typeExpr.(*ast.ArrayType)
+		isBool := typeName(f.typeExpr) == "bool" && !isSlice
+		options, err := parseOptions(f.tag.Get("options"))
+		if err != nil {
+			return nil, fmt.Errorf("input %s: %s", name, err.Error())
+		}
+		if input.Type == "" {
+			switch {
+			case isBool:
+				input.Type = task.TypeCheckbox
+			case len(options) > 0 && isSlice:
+				input.Type = task.TypeCheckboxList
+			case len(options) > 0:
+				input.Type = task.TypeSelector
+			default:
+				input.Type = task.TypeInput
+			}
+		}
+		if !task.IsKnownType(input.Type) {
+			return nil, fmt.Errorf("input %s: unknown component type %s", name, input.Type)
+		}
+		switch input.Type {
+		case task.TypeSelector, task.TypeDevopsSelect, task.TypeSelectInput:
+			input.Options = options
+			input.MultiSelect = isSlice
+		case task.TypeCheckboxList:
+			input.List = options
+		case task.TypeEnumInput:
+			for _, o := range options {
+				o.Value, o.Label, o.Id, o.Name = o.Id, o.Name, "", ""
+			}
+			input.List = options
+		}
+		input.Default = typedDefault(input.Default, isBool, isSlice)
+		inputs = append(inputs, input)
+	}
+	return inputs, nil
+}
+
+// structOutputs 按字段标签生成输出声明
+// 支持的标签：bk 或 json（名称）、desc、type（string、artifact、report）、sensitive
+// 未指定 type 时 ArtifactData 与 ReportData 类型的字段分别视为 artifact 与 report
+func structOutputs(fields []*structField, structs map[string][]*structField) (task.Outputs, error) {
+	var outputs task.Outputs
+	for _, f := range flattenFields(fields, structs, 0) {
+		name, _ := fieldName(f)
+		if name == "" {
+			continue
+		}
+		if outputs.Get(name) != nil {
+			return nil, fmt.Errorf("output %s is declared more than once", name)
+		}
+		title, desc := docText(f)
+		if desc != "" {
+			title = strings.TrimSpace(title + " " + desc)
+		}
+		output := &task.Output{
+			Name:        name,
+			Description: tagOr(f.tag, "desc", title),
+			Type:        f.tag.Get("type"),
+		}
+		output.IsSensitive, _ = strconv.ParseBool(f.tag.Get("sensitive"))
+		if output.Type == "" {
+			switch typeName(f.typeExpr) {
+			case "ArtifactData":
+				output.Type = task.OutputTypeArtifact
+			case "ReportData":
+				output.Type = task.OutputTypeReport
+			default:
+				output.Type = task.OutputTypeString
+			}
+		}
+		switch output.Type {
+		case task.OutputTypeString, task.OutputTypeArtifact, task.OutputTypeReport:
+		default:
+			return nil, fmt.Errorf("output %s: unknown output type %s", name, output.Type)
+		}
+		outputs = append(outputs, output)
+	}
+	return outputs, nil
+}
+
+func tagOr(tag reflect.StructTag, key string, fallback string) string {
+	if v, ok := tag.Lookup(key); ok {
+		return v
+	}
+	return fallback
+}
+
+// parseOptions 解析 options 标签，格式为 id:名称,id:名称，省略名称时使用 id
+func parseOptions(s string) ([]*task.Option, error) {
+	if strings.TrimSpace(s) == "" {
+		return nil, nil
+	}
+	var options []*task.Option
+	for _, item := range strings.Split(s, ",") {
+		item = strings.TrimSpace(item)
+		if item == "" {
+			continue
+		}
+		id, name := item, item
+		if i := strings.Index(item, ":"); i >= 0 {
+			id, name = strings.TrimSpace(item[:i]), strings.TrimSpace(item[i+1:])
+		}
+		if id == "" {
+			return nil, fmt.Errorf("invalid option %q", item)
+		}
+		options = append(options, &task.Option{Id: id, Name: name})
+	}
+	return options, nil
+}
+
+// typedDefault 按字段类型转换 default= 的值
+func typedDefault(v interface{}, isBool bool, isSlice bool) interface{} {
+	s, ok := v.(string)
+	if !ok {
+		return v
+	}
+	switch {
+	case isBool:
+		if b, err := strconv.ParseBool(s); err == nil {
+			return b
+		}
+	case isSlice:
+		var list []interface{}
+		if err := json.Unmarshal([]byte(s), &list); err == nil {
+			return list
+		}
+		list = []interface{}{}
+		for _, item := range strings.Split(s, ",") {
+			if item = strings.TrimSpace(item); item != "" {
+				list = append(list, item)
+			}
+		}
+		return list
+	}
+	return s
+}
+
+// rawField json 对象中的一个字段
+type rawField struct {
+	key   string
+	value json.RawMessage
+}
+
+// rawObject 保留字段顺序的 json 对象
+type rawObject []*rawField
+
+func parseRawObject(data []byte) (rawObject, error) {
+	dec := json.NewDecoder(bytes.NewReader(data))
+	tok, err := dec.Token()
+	if err != nil {
+		return nil, err
+	}
+	if delim, ok := tok.(json.Delim); !ok || delim != '{' {
+		return nil, errors.New("expect json object")
+	}
+	var obj rawObject
+	for dec.More() {
+		tok, err = dec.Token()
+		if err != nil {
+			return nil, err
+		}
+		var value json.RawMessage
+		if err = dec.Decode(&value); err != nil {
+			return nil, err
+		}
+		obj = append(obj, &rawField{key: tok.(string), value: value})
+	}
+	return obj, nil
+}
+
+func (o rawObject) get(key string) *rawField {
+	for _, f := range o {
+		if f.key == key {
+			return f
+		}
+	}
+	return nil
+}
+
+// set 设置字段，已存在时保留原有位置
+func (o *rawObject) set(key string, value json.RawMessage) {
+	if f := o.get(key); f != nil {
+		f.value = value
+		return
+	}
+	*o = append(*o, &rawField{key: key, value: value})
+}
+
+// remove 删除字段
+func (o *rawObject) remove(key string) {
+	for i, f := range *o {
+		if f.key == key {
+			*o = append((*o)[:i], (*o)[i+1:]...)
+			return
+		}
+	}
+}
+
+func (o rawObject) MarshalJSON() ([]byte, error) {
+	var buf bytes.Buffer
+	buf.WriteByte('{')
+	for i, f := range o {
+		if i > 0 {
+			buf.WriteByte(',')
+		}
+		k, err := json.Marshal(f.key)
+		if err != nil {
+			return nil, err
+		}
+		buf.Write(k)
+		buf.WriteByte(':')
+		buf.Write(f.value)
+	}
+	buf.WriteByte('}')
+	return buf.Bytes(), nil
+}
+
+func (o rawObject) marshalIndent() ([]byte, error) {
+	data, err := json.Marshal(o)
+	if err != nil {
+		return nil, err
+	}
+	var buf bytes.Buffer
+	if err = json.Indent(&buf, data, "", "  "); err != nil {
+		return nil, err
+	}
+	buf.WriteByte('\n')
+	return buf.Bytes(), nil
+}
+
+// updateSection 使用生成的字段替换 input 或 output，已有字段中生成范围以外的配置保留
+func (o *rawObject) updateSection(key string, n int, item func(i int) (string, interface{}), generatedKeys []string) error {
+	existing := make(map[string]rawObject)
+	if f := o.get(key); f != nil && string(f.value) != "null" {
+		section, err := parseRawObject(f.value)
+		if err != nil {
+			return err
+		}
+		for _, field := range section {
+			if obj, err := parseRawObject(field.value); err == nil {
+				existing[field.key] = obj
+			}
+		}
+	}
+
+	var section rawObject
+	for i := 0; i < n; i++ {
+		name, value := item(i)
+		data, err := json.Marshal(value)
+		if err != nil {
+			return err
+		}
+		generated, err := parseRawObject(data)
+		if err != nil {
+			return err
+		}
+		obj := existing[name]
+		for _, k := range generatedKeys {
+			if generated.get(k) == nil {
+				obj.remove(k)
+			}
+		}
+		for _, f := range generated {
+			obj.set(f.key, f.value)
+		}
+		merged, err := json.Marshal(obj)
+		if err != nil {
+			return err
+		}
+		section = append(section, &rawField{key: name, value: merged})
+	}
+	for name := range existing {
+		if section.get(name) == nil {
+			fmt.Fprintf(os.Stdout, "remove %s %s\n", key, name)
+		}
+	}
+
+	data, err := json.Marshal(section)
+	if err != nil {
+		return err
+	}
+	o.set(key, data)
+	return nil
+}
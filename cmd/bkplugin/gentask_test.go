@@ -0,0 +1,251 @@
+package main
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/ci-plugins/golang-plugin-sdk/task"
+)
+
+const gentaskSource = `package demo
+
+import "github.com/ci-plugins/golang-plugin-sdk/api"
+
+type Common struct {
+	// Token 访问凭证
+	Token string ` + "`bk:\"token,required\" type:\"vuex-input\"`" + `
+}
+
+type Input struct {
+	Common
+	// RepoUrl 仓库地址
+	// 支持 http 与 ssh
+	RepoUrl string ` + "`bk:\"repo_url,required\" placeholder:\"http://\"`" + `
+	Mode    string ` + "`bk:\"mode,default=fast\" label:\"模式\" options:\"fast:快速,slow\"`" + `
+	Langs   []string ` + "`bk:\"langs,default=go,java\" options:\"go,java\"`" + `
+	Enabled bool ` + "`bk:\"enabled,default=true\"`" + `
+	Skipped string ` + "`bk:\"-\"`" + `
+	hidden  string
+}
+
+type Output struct {
+	// Version 版本号
+	Version string ` + "`json:\"version\"`" + `
+	Package api.ArtifactData ` + "`bk:\"package\"`" + `
+	Report  *api.ReportData ` + "`bk:\"report\"`" + `
+	Secret  string ` + "`bk:\"secret\" sensitive:\"true\" desc:\"密钥\"`" + `
+}
+`
+
+func writeSource(t *testing.T, src string) string {
+	t.Helper()
+	dir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(dir, "demo.go"), []byte(src), 0644); err != nil {
+		t.Fatal(err)
+	}
+	// 测试文件中的结构体不参与生成
+	if err := os.WriteFile(filepath.Join(dir, "demo_test.go"), []byte("package demo\n\ntype Input struct{ X string }\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	return dir
+}
+
+func TestStructInputs(t *testing.T) {
+	structs, err := parseStructs(writeSource(t, gentaskSource))
+	if err != nil {
+		t.Fatalf("parseStructs() error = %v", err)
+	}
+	inputs, err := structInputs(structs["Input"], structs)
+	if err != nil {
+		t.Fatalf("structInputs() error = %v", err)
+	}
+
+	var names []string
+	for _, i := range inputs {
+		names = append(names, i.Name)
+	}
+	if want := []string{"token", "repo_url", "mode", "langs", "enabled"}; !reflect.DeepEqual(names, want) {
+		t.Fatalf("inputs = %v, want %v", names, want)
+	}
+
+	repo := inputs.Get("repo_url")
+	if repo.Label != "仓库地址" || repo.Desc != "支持 http 与 ssh" || !repo.Required || repo.Placeholder != "http://" || repo.Type != task.TypeInput {
+		t.Errorf("repo_url = %+v", repo)
+	}
+	mode := inputs.Get("mode")
+	if mode.Label != "模式" || mode.Type != task.TypeSelector || mode.Default != "fast" || len(mode.Options) != 2 ||
+		mode.Options[0].Name != "快速" || mode.Options[1].Name != "slow" || mode.MultiSelect {
+		t.Errorf("mode = %+v", mode)
+	}
+	langs := inputs.Get("langs")
+	if langs.Type != task.TypeCheckboxList || len(langs.List) != 2 || !reflect.DeepEqual(langs.Default, []interface{}{"go", "java"}) {
+		t.Errorf("langs = %+v", langs)
+	}
+	if enabled := inputs.Get("enabled"); enabled.Type != task.TypeCheckbox || enabled.Default != true {
+		t.Errorf("enabled = %+v", enabled)
+	}
+}
+
+func TestStructOutputs(t *testing.T) {
+	structs, err := parseStructs(writeSource(t, gentaskSource))
+	if err != nil {
+		t.Fatalf("parseStructs() error = %v", err)
+	}
+	outputs, err := structOutputs(structs["Output"], structs)
+	if err != nil {
+		t.Fatalf("structOutputs() error = %v", err)
+	}
+	tests := []struct {
+		name      string
+		typ       string
+		desc      string
+		sensitive bool
+	}{
+		{"version", task.OutputTypeString, "版本号", false},
+		{"package", task.OutputTypeArtifact, "", false},
+		{"report", task.OutputTypeReport, "", false},
+		{"secret", task.OutputTypeString, "密钥", true},
+	}
+	if len(outputs) != len(tests) {
+		t.Fatalf("outputs = %d, want %d", len(outputs), len(tests))
+	}
+	for i, tt := range tests {
+		o := outputs[i]
+		if o.Name != tt.name || o.Type != tt.typ || o.Description != tt.desc || o.IsSensitive != tt.sensitive {
+			t.Errorf("output %d = %+v, want %+v", i, o, tt)
+		}
+	}
+}
+
+func TestStructInvalid(t *testing.T) {
+	tests := map[string]string{
+		"duplicate":      "type Input struct {\n\tA string `bk:\"a\"`\n\tB string `json:\"a\"`\n}",
+		"unknown type":   "type Input struct {\n\tA string `type:\"bogus\"`\n}",
+		"invalid option": "type Input struct {\n\tA string `options:\":x\"`\n}",
+	}
+	for name, src := range tests {
+		t.Run(name, func(t *testing.T) {
+			structs, err := parseStructs(writeSource(t, "package demo\n\n"+src+"\n"))
+			if err != nil {
+				t.Fatalf("parseStructs() error = %v", err)
+			}
+			if _, err = structInputs(structs["Input"], structs); err == nil {
+				t.Errorf("structInputs() error = nil")
+			}
+		})
+	}
+
+	structs, _ := parseStructs(writeSource(t, "package demo\n\ntype Output struct {\n\tA string `type:\"number\"`\n}\n"))
+	if _, err := structOutputs(structs["Output"], structs); err == nil {
+		t.Errorf("structOutputs() with unknown type error = nil")
+	}
+}
+
+func TestTypedDefault(t *testing.T) {
+	tests := []struct {
+		in      interface{}
+		isBool  bool
+		isSlice bool
+		want    interface{}
+	}{
+		{"x", false, false, "x"},
+		{"true", true, false, true},
+		{"yes", true, false, "yes"},
+		{`["a","b"]`, false, true, []interface{}{"a", "b"}},
+		{"a, b,", false, true, []interface{}{"a", "b"}},
+		{nil, true, false, nil},
+	}
+	for _, tt := range tests {
+		if got := typedDefault(tt.in, tt.isBool, tt.isSlice); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("typedDefault(%v, %v, %v) = %#v, want %#v", tt.in, tt.isBool, tt.isSlice, got, tt.want)
+		}
+	}
+}
+
+func TestGentaskMerge(t *testing.T) {
+	dir := writeSource(t, gentaskSource)
+	file := filepath.Join(dir, "task.json")
+	existing := `{
+  "atomCode": "demo",
+  "execution": {"language": "golang"},
+  "input": {
+    "mode": {"label": "旧标签", "type": "selector", "required": true, "rely": {"operation": "AND", "expression": [{"key": "enabled", "value": true}]}},
+    "removed": {"type": "vuex-input"}
+  },
+  "output": null,
+  "releaseInfo": {"version": "1.0.0"}
+}`
+	if err := os.WriteFile(file, []byte(existing), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	if code := gentaskCommand([]string{"-task", file, "-src", dir}); code != 0 {
+		t.Fatalf("gentask exit code = %d", code)
+	}
+	data, err := os.ReadFile(file)
+	if err != nil {
+		t.Fatal(err)
+	}
+	doc, err := parseRawObject(data)
+	if err != nil {
+		t.Fatalf("generated task.json: %v", err)
+	}
+
+	var keys []string
+	for _, f := range doc {
+		keys = append(keys, f.key)
+	}
+	if want := []string{"atomCode", "execution", "input", "output", "releaseInfo"}; !reflect.DeepEqual(keys, want) {
+		t.Errorf("top level keys = %v, want %v", keys, want)
+	}
+
+	var input map[string]map[string]interface{}
+	if err = json.Unmarshal(doc.get("input").value, &input); err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := input["removed"]; ok {
+		t.Errorf("input not declared in the struct should be removed")
+	}
+	mode := input["mode"]
+	if mode["label"] != "模式" || mode["rely"] == nil {
+		t.Errorf("mode = %v, want generated label and kept rely", mode)
+	}
+	if _, ok := mode["required"]; ok {
+		t.Errorf("generated key required should be removed when no longer set: %v", mode)
+	}
+
+	tk, err := task.Parse(data)
+	if err != nil {
+		t.Fatalf("task.Parse() error = %v", err)
+	}
+	if tk.Input[0].Name != "token" || len(tk.Output) != 4 || tk.Input.Get("mode").Rely == nil {
+		t.Errorf("generated task = %s", data)
+	}
+}
+
+func TestGentaskCreate(t *testing.T) {
+	dir := writeSource(t, gentaskSource)
+	file := filepath.Join(dir, "task.json")
+	if code := gentaskCommand([]string{"-task", file, "-src", dir, "-output", "", "-atom", "demo_atom"}); code != 0 {
+		t.Fatalf("gentask exit code = %d", code)
+	}
+	data, err := os.ReadFile(file)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tk, err := task.Parse(data)
+	if err != nil {
+		t.Fatalf("task.Parse() error = %v", err)
+	}
+	if tk.AtomCode != "demo_atom" || len(tk.Input) != 5 || len(tk.Output) != 0 || !strings.HasSuffix(string(data), "\n") {
+		t.Errorf("created task.json = %s", data)
+	}
+
+	if code := gentaskCommand([]string{"-task", file, "-src", dir, "-input", "Missing"}); code == 0 {
+		t.Errorf("gentask with missing struct exit code = 0")
+	}
+}
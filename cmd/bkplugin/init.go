@@ -0,0 +1,106 @@
+package main
+
+import (
+	"bytes"
+	"embed"
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"text/template"
+)
+
+//go:embed templates/*.tmpl
+var templates embed.FS
+
+// scaffoldFiles 模板与生成的文件路径
+var scaffoldFiles = []struct {
+	template string
+	path     string
+}{
+	{"main.go.tmpl", "main.go"},
+	{"main_test.go.tmpl", "main_test.go"},
+	{"task.json.tmpl", "task.json"},
+	{"message_zh_CN.properties.tmpl", "i18n/message_zh_CN.properties"},
+	{"message_en_US.properties.tmpl", "i18n/message_en_US.properties"},
+	{"translation.go.tmpl", "translation/translation.go"},
+	{"go.mod.tmpl", "go.mod"},
+	{"Makefile.tmpl", "Makefile"},
+	{"gitignore.tmpl", ".gitignore"},
+}
+
+var atomCodeRegexp = regexp.MustCompile(`^[a-zA-Z][a-zA-Z0-9_-]*$`)
+
+// scaffold 模板参数
+type scaffold struct {
+	AtomCode string
+	Module   string
+}
+
+func initCommand(args []string) int {
+	fs := flag.NewFlagSet("init", flag.ExitOnError)
+	dir := fs.String("dir", "", "项目目录，默认为 ./<atomCode>")
+	module := fs.String("module", "", "go module 路径，默认为 atomCode")
+	force := fs.Bool("force", false, "覆盖已存在的文件")
+	fs.Usage = func() {
+		fmt.Fprintf(os.Stderr, "usage: bkplugin init [flags] <atomCode>\n")
+		fs.PrintDefaults()
+	}
+	fs.Parse(args)
+	if fs.NArg() != 1 {
+		fs.Usage()
+		return 1
+	}
+
+	s := &scaffold{AtomCode: fs.Arg(0), Module: *module}
+	if !atomCodeRegexp.MatchString(s.AtomCode) {
+		fmt.Fprintf(os.Stderr, "invalid atomCode %s, only letters, numbers, dashes and underscores are allowed\n", s.AtomCode)
+		return 1
+	}
+	if s.Module == "" {
+		s.Module = s.AtomCode
+	}
+	if *dir == "" {
+		*dir = s.AtomCode
+	}
+
+	if !*force {
+		for _, f := range scaffoldFiles {
+			if _, err := os.Stat(filepath.Join(*dir, f.path)); err == nil {
+				fmt.Fprintf(os.Stderr, "%s already exists, use -force to overwrite\n", filepath.Join(*dir, f.path))
+				return 1
+			}
+		}
+	}
+
+	for _, f := range scaffoldFiles {
+		path := filepath.Join(*dir, f.path)
+		if err := s.render(f.template, path); err != nil {
+			fmt.Fprintf(os.Stderr, "generate %s error %s\n", path, err.Error())
+			return 1
+		}
+		fmt.Fprintf(os.Stdout, "create %s\n", path)
+	}
+
+	fmt.Fprintf(os.Stdout, "\nplugin %s created, next steps:\n", s.AtomCode)
+	fmt.Fprintf(os.Stdout, "  cd %s\n", *dir)
+	fmt.Fprintf(os.Stdout, "  go mod tidy\n")
+	fmt.Fprintf(os.Stdout, "  make test\n")
+	return 0
+}
+
+func (s *scaffold) render(name string, path string) error {
+	t, err := template.ParseFS(templates, "templates/"+name)
+	if err != nil {
+		return err
+	}
+	var buf bytes.Buffer
+	if err = t.Execute(&buf, s); err != nil {
+		return err
+	}
+	if err = os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return err
+	}
+	return os.WriteFile(path, buf.Bytes(), 0644)
+}
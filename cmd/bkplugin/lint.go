@@ -0,0 +1,448 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"go/ast"
+	"go/parser"
+	"go/token"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/magiconair/properties"
+
+	"github.com/ci-plugins/golang-plugin-sdk/task"
+)
+
+// 检查问题的级别
+const (
+	levelError   = "error"
+	levelWarning = "warning"
+)
+
+// outputFuncs 代码中设置输出的函数及其设置的输出类型，第一个参数为输出名称
+// 类型为空时由第二个参数的构造函数确定
+var outputFuncs = map[string]string{
+	"AddOutputData":     "",
+	"SetStringOutput":   task.OutputTypeString,
+	"SetJSONOutput":     task.OutputTypeString,
+	"AddArtifactOutput": task.OutputTypeArtifact,
+	"AddReportOutput":   task.OutputTypeReport,
+}
+
+// outputConstructors 构造输出数据的函数对应的输出类型
+var outputConstructors = map[string]string{
+	"NewStringData":           task.OutputTypeString,
+	"NewArtifactData":         task.OutputTypeArtifact,
+	"NewReportData":           task.OutputTypeReport,
+	"NewInternalReportData":   task.OutputTypeReport,
+	"NewThirdpartyReportData": task.OutputTypeReport,
+}
+
+// problem 检查发现的问题
+type problem struct {
+	level   string
+	file    string
+	message string
+}
+
+// linter 收集检查问题
+type linter struct {
+	problems []*problem
+}
+
+func (l *linter) errorf(file string, format string, v ...interface{}) {
+	l.problems = append(l.problems, &problem{level: levelError, file: file, message: fmt.Sprintf(format, v...)})
+}
+
+func (l *linter) warnf(file string, format string, v ...interface{}) {
+	l.problems = append(l.problems, &problem{level: levelWarning, file: file, message: fmt.Sprintf(format, v...)})
+}
+
+func (l *linter) errors() int {
+	n := 0
+	for _, p := range l.problems {
+		if p.level == levelError {
+			n++
+		}
+	}
+	return n
+}
+
+func lintCommand(args []string) int {
+	fs := flag.NewFlagSet("lint", flag.ExitOnError)
+	taskFile := fs.String("task", "task.json", "插件的 task.json 路径")
+	src := fs.String("src", ".", "插件代码目录，用于检查 AddOutputData 使用的输出名称，为空时不检查")
+	i18nDir := fs.String("i18n", "i18n", "国际化文件目录，不存在时不检查")
+	fs.Parse(args)
+
+	l := new(linter)
+	t := l.lintTask(*taskFile)
+	if t != nil {
+		if *src != "" {
+			l.lintOutputUsage(t, *taskFile, *src)
+		}
+		l.lintI18n(t, *i18nDir)
+	}
+
+	for _, p := range l.problems {
+		fmt.Fprintf(os.Stdout, "%s: %s: %s\n", p.file, p.level, p.message)
+	}
+	if n := l.errors(); n > 0 {
+		fmt.Fprintf(os.Stdout, "%d errors, %d warnings\n", n, len(l.problems)-n)
+		return 1
+	}
+	fmt.Fprintf(os.Stdout, "%s ok, %d warnings\n", *taskFile, len(l.problems))
+	return 0
+}
+
+// lintTask 检查 task.json 本身，无法解析时返回 nil
+func (l *linter) lintTask(file string) *task.Task {
+	data, err := ioutil.ReadFile(file)
+	if err != nil {
+		l.errorf(file, "read failed: %s", err.Error())
+		return nil
+	}
+	t, err := task.Parse(data)
+	if err != nil {
+		if serr, ok := err.(*json.SyntaxError); ok {
+			line, col := position(data, serr.Offset)
+			l.errorf(file, "invalid json at line %d column %d: %s", line, col, serr.Error())
+		} else {
+			l.errorf(file, "invalid task.json: %s", err.Error())
+		}
+		return nil
+	}
+
+	if t.AtomCode == "" {
+		l.errorf(file, "atomCode is empty")
+	}
+	l.lintInputs(t, file)
+	l.lintOutputs(t, file)
+	l.lintExecution(t, file)
+	return t
+}
+
+func (l *linter) lintInputs(t *task.Task, file string) {
+	for _, name := range t.Input.Duplicates() {
+		l.errorf(file, "input %s is declared more than once", name)
+	}
+	for _, input := range t.Input {
+		if !task.IsKnownType(input.Type) {
+			l.errorf(file, "input %s: unknown component type %q", input.Name, input.Type)
+		}
+		if input.Rely != nil {
+			switch input.Rely.Operation {
+			case "", task.RelyAnd, task.RelyOr, task.RelyNot:
+			default:
+				l.errorf(file, "input %s: unknown rely operation %q", input.Name, input.Rely.Operation)
+			}
+			for _, e := range input.Rely.Expression {
+				if e.Key == input.Name {
+					l.errorf(file, "input %s: rely refers to itself", input.Name)
+				} else if t.Input.Get(e.Key) == nil {
+					l.errorf(file, "input %s: rely refers to unknown input %q", input.Name, e.Key)
+				}
+			}
+		}
+		switch input.Type {
+		case task.TypeEnumInput, task.TypeCheckboxList:
+			if len(input.Choices()) == 0 {
+				l.errorf(file, "input %s: %s requires list", input.Name, input.Type)
+			}
+		}
+		for _, c := range input.Choices() {
+			if c.Key() == "" {
+				l.errorf(file, "input %s: option without id", input.Name)
+			}
+		}
+	}
+}
+
+func (l *linter) lintOutputs(t *task.Task, file string) {
+	for _, name := range t.Output.Duplicates() {
+		l.errorf(file, "output %s is declared more than once", name)
+	}
+	for _, output := range t.Output {
+		switch output.DataType() {
+		case task.OutputTypeString, task.OutputTypeArtifact, task.OutputTypeReport:
+		default:
+			l.errorf(file, "output %s: unknown output type %q", output.Name, output.Type)
+		}
+	}
+}
+
+func (l *linter) lintExecution(t *task.Task, file string) {
+	e := t.Execution
+	if e == nil {
+		l.errorf(file, "execution is missing")
+		return
+	}
+	if e.Language == "" {
+		l.errorf(file, "execution.language is empty")
+	}
+	if len(e.Os) == 0 {
+		if strings.TrimSpace(e.Target) == "" {
+			l.errorf(file, "execution.target is empty and execution.os is not declared")
+		}
+		return
+	}
+
+	platforms := make(map[string]bool)
+	defaults := 0
+	for i, o := range e.Os {
+		goos, ok := goosOf(o.OsName)
+		if !ok {
+			l.errorf(file, "execution.os[%d]: unknown osName %q, expect linux, windows or macOS", i, o.OsName)
+			goos = o.OsName
+		}
+		arch := o.OsArch
+		if arch == "" {
+			arch = "amd64"
+		}
+		platform := goos + "/" + arch
+		if platforms[platform] {
+			l.errorf(file, "execution.os[%d]: %s is declared more than once", i, platform)
+		}
+		platforms[platform] = true
+		if strings.TrimSpace(o.Target) == "" {
+			l.errorf(file, "execution.os[%d]: target of %s is empty", i, platform)
+		} else if goos == "windows" && !strings.HasSuffix(targetFile(o.Target), ".exe") {
+			l.warnf(file, "execution.os[%d]: windows target %q has no .exe suffix", i, o.Target)
+		}
+		if o.DefaultFlag {
+			defaults++
+		}
+	}
+	if defaults > 1 {
+		l.errorf(file, "execution.os: %d entries have defaultFlag, expect at most one", defaults)
+	}
+}
+
+// lintOutputUsage 比较 task.json 声明的输出与代码中设置的输出
+func (l *linter) lintOutputUsage(t *task.Task, taskFile string, src string) {
+	usages, dynamic, err := scanOutputs(src)
+	if err != nil {
+		l.errorf(src, "scan code failed: %s", err.Error())
+		return
+	}
+
+	names := make([]string, 0, len(usages))
+	for name := range usages {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		u := usages[name]
+		output := t.Output.Get(name)
+		if output == nil {
+			l.errorf(u.pos, "output %q is not declared in task.json", name)
+			continue
+		}
+		if u.outputType != "" && u.outputType != output.DataType() {
+			l.errorf(u.pos, "output %q is %s in code but %s in task.json", name, u.outputType, output.DataType())
+		}
+	}
+	if dynamic {
+		// 存在非常量的输出名称时无法确定未使用的输出
+		return
+	}
+	for _, output := range t.Output {
+		if _, ok := usages[output.Name]; !ok {
+			l.warnf(taskFile, "output %s is declared but never set in code", output.Name)
+		}
+	}
+}
+
+// outputUsage 代码中设置输出的位置
+type outputUsage struct {
+	pos        string
+	outputType string
+}
+
+// scanOutputs 扫描代码中设置的输出名称，名称不是字符串常量时 dynamic 为 true
+func scanOutputs(dir string) (map[string]*outputUsage, bool, error) {
+	fset := token.NewFileSet()
+	var files []*ast.File
+	err := filepath.Walk(dir, func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		if info.IsDir() {
+			name := info.Name()
+			if path != dir && (name == "vendor" || name == "testdata" || strings.HasPrefix(name, ".") || strings.HasPrefix(name, "_")) {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		if !strings.HasSuffix(path, ".go") || strings.HasSuffix(path, "_test.go") {
+			return nil
+		}
+		f, err := parser.ParseFile(fset, path, nil, 0)
+		if err != nil {
+			return err
+		}
+		files = append(files, f)
+		return nil
+	})
+	if err != nil {
+		return nil, false, err
+	}
+
+	consts := stringConsts(files)
+	usages := make(map[string]*outputUsage)
+	dynamic := false
+	for _, f := range files {
+		ast.Inspect(f, func(n ast.Node) bool {
+			call, ok := n.(*ast.CallExpr)
+			if !ok || len(call.Args) == 0 {
+				return true
+			}
+			outputType, ok := outputFuncs[funcName(call.Fun)]
+			if !ok {
+				return true
+			}
+			name, ok := stringValueOf(call.Args[0], consts)
+			if !ok {
+				dynamic = true
+				return true
+			}
+			u := &outputUsage{pos: fset.Position(call.Pos()).String(), outputType: outputType}
+			if outputType == "" && len(call.Args) > 1 {
+				if c, ok := call.Args[1].(*ast.CallExpr); ok {
+					u.outputType = outputConstructors[funcName(c.Fun)]
+				}
+			}
+			if _, ok := usages[name]; !ok {
+				usages[name] = u
+			}
+			return true
+		})
+	}
+	return usages, dynamic, nil
+}
+
+// stringConsts 收集字符串常量，用于解析以常量作为名称的输出
+func stringConsts(files []*ast.File) map[string]string {
+	consts := make(map[string]string)
+	for _, f := range files {
+		for _, decl := range f.Decls {
+			gen, ok := decl.(*ast.GenDecl)
+			if !ok || gen.Tok != token.CONST {
+				continue
+			}
+			for _, spec := range gen.Specs {
+				vs := spec.(*ast.ValueSpec)
+				for i, name := range vs.Names {
+					if i >= len(vs.Values) {
+						continue
+					}
+					if lit, ok := vs.Values[i].(*ast.BasicLit); ok && lit.Kind == token.STRING {
+						if s, err := strconv.Unquote(lit.Value); err == nil {
+							consts[name.Name] = s
+						}
+					}
+				}
+			}
+		}
+	}
+	return consts
+}
+
+func stringValueOf(expr ast.Expr, consts map[string]string) (string, bool) {
+	switch e := expr.(type) {
+	case *ast.BasicLit:
+		if e.Kind != token.STRING {
+			return "", false
+		}
+		s, err := strconv.Unquote(e.Value)
+		return s, err == nil
+	case *ast.Ident:
+		s, ok := consts[e.Name]
+		return s, ok
+	case *ast.SelectorExpr:
+		s, ok := consts[e.Sel.Name]
+		return s, ok
+	}
+	return "", false
+}
+
+// funcName 调用的函数名，去掉包名与接收者
+func funcName(expr ast.Expr) string {
+	switch e := expr.(type) {
+	case *ast.Ident:
+		return e.Name
+	case *ast.SelectorExpr:
+		return e.Sel.Name
+	case *ast.IndexExpr:
+		return funcName(e.X)
+	case *ast.IndexListExpr:
+		return funcName(e.X)
+	}
+	return ""
+}
+
+// lintI18n 检查每个 message_*.properties 文件中都有 task.json 中文案对应的国际化 key
+func (l *linter) lintI18n(t *task.Task, dir string) {
+	files, err := ioutil.ReadDir(dir)
+	if os.IsNotExist(err) {
+		return
+	}
+	if err != nil {
+		l.errorf(dir, "read i18n dir failed: %s", err.Error())
+		return
+	}
+
+	var keys []string
+	for _, input := range t.Input {
+		if input.Label != "" {
+			keys = append(keys, "input."+input.Name+".label")
+		}
+		if input.Desc != "" {
+			keys = append(keys, "input."+input.Name+".desc")
+		}
+		if input.Placeholder != "" {
+			keys = append(keys, "input."+input.Name+".placeholder")
+		}
+	}
+	for _, output := range t.Output {
+		if output.Description != "" {
+			keys = append(keys, "output."+output.Name+".description")
+		}
+	}
+
+	for _, f := range files {
+		name := f.Name()
+		if f.IsDir() || !strings.HasPrefix(name, "message_") || !strings.HasSuffix(name, ".properties") {
+			continue
+		}
+		path := filepath.Join(dir, name)
+		p, err := properties.LoadFile(path, properties.UTF8)
+		if err != nil {
+			l.errorf(path, "load failed: %s", err.Error())
+			continue
+		}
+		for _, key := range keys {
+			if _, ok := p.Get(key); !ok {
+				l.errorf(path, "missing key %s", key)
+			}
+		}
+	}
+}
+
+// position 将字节偏移转换为行号与列号
+func position(data []byte, offset int64) (int, int) {
+	if offset > int64(len(data)) {
+		offset = int64(len(data))
+	}
+	before := data[:offset]
+	line := bytes.Count(before, []byte("\n")) + 1
+	col := int(offset) - bytes.LastIndexByte(before, '\n')
+	return line, col
+}
@@ -0,0 +1,264 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"testing"
+)
+
+const lintTaskJSON = `{
+  "atomCode": "demo",
+  "input": {
+    "mode": {"label": "模式", "type": "selector", "options": [{"id": "fast"}, {"name": "no id"}]},
+    "mode": {"type": "vuex-input"},
+    "langs": {"type": "atom-checkbox-list"},
+    "script": {"type": "bogus", "rely": {"operation": "XOR", "expression": [{"key": "script"}, {"key": "missing"}]}}
+  },
+  "output": {
+    "ver": {"type": "string", "description": "版本"},
+    "pkg": {"type": "artifact"},
+    "unused": {"type": "string"},
+    "odd": {"type": "number"},
+    "plain": {}
+  },
+  "execution": {
+    "language": "golang",
+    "os": [
+      {"osName": "linux", "target": "./app"},
+      {"osName": "linux", "osArch": "amd64", "target": "./app", "defaultFlag": true},
+      {"osName": "windows", "target": "app", "defaultFlag": true},
+      {"osName": "solaris", "target": ""},
+      {"osName": "macOS", "osArch": "arm64", "target": "./app"},
+      {"osName": "darwin", "osArch": "arm64", "target": "./app"},
+      {"osName": "Windows", "osArch": "arm64", "target": "app.exe"}
+    ]
+  }
+}`
+
+const lintSource = `package demo
+
+import "github.com/ci-plugins/golang-plugin-sdk/api"
+
+const pkgName = "pkg"
+
+func run(ctx *api.Context, name string) {
+	api.AddOutputData("ver", api.NewStringData("1"))
+	ctx.AddOutputData(pkgName, api.NewStringData("a"))
+	api.SetJSONOutput[map[string]string]("extra", nil)
+	api.AddArtifactOutput("plain", "f")
+}
+`
+
+// lintFixture 在临时目录中写入 task.json 与代码，返回 task.json 路径与代码目录
+func lintFixture(t *testing.T, taskJSON string, src string) (string, string) {
+	t.Helper()
+	dir := t.TempDir()
+	file := filepath.Join(dir, "task.json")
+	if err := os.WriteFile(file, []byte(taskJSON), 0644); err != nil {
+		t.Fatal(err)
+	}
+	srcDir := filepath.Join(dir, "src")
+	for name, content := range map[string]string{
+		"main.go":         src,
+		"main_test.go":    "package demo\n\nfunc init() { api.AddOutputData(\"from_test\", nil) }\n",
+		"vendor/v/v.go":   "package v\n\nfunc init() { api.AddOutputData(\"from_vendor\", nil) }\n",
+		"testdata/t/t.go": "package t\n\nfunc init() { api.AddOutputData(\"from_testdata\", nil) }\n",
+	} {
+		path := filepath.Join(srcDir, name)
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return file, srcDir
+}
+
+func messages(l *linter, level string) []string {
+	var list []string
+	for _, p := range l.problems {
+		if p.level == level {
+			list = append(list, p.message)
+		}
+	}
+	sort.Strings(list)
+	return list
+}
+
+func assertProblems(t *testing.T, got []string, want []string) {
+	t.Helper()
+	sort.Strings(want)
+	if strings.Join(got, "\n") != strings.Join(want, "\n") {
+		t.Errorf("problems:\n%s\nwant:\n%s", strings.Join(got, "\n"), strings.Join(want, "\n"))
+	}
+}
+
+func TestLintTask(t *testing.T) {
+	file, _ := lintFixture(t, lintTaskJSON, lintSource)
+	l := new(linter)
+	if l.lintTask(file) == nil {
+		t.Fatalf("lintTask() = nil, problems %v", messages(l, levelError))
+	}
+	assertProblems(t, messages(l, levelError), []string{
+		"input mode is declared more than once",
+		"input mode: option without id",
+		"input langs: atom-checkbox-list requires list",
+		`input script: unknown component type "bogus"`,
+		`input script: unknown rely operation "XOR"`,
+		"input script: rely refers to itself",
+		`input script: rely refers to unknown input "missing"`,
+		`output odd: unknown output type "number"`,
+		"execution.os[1]: linux/amd64 is declared more than once",
+		`execution.os[3]: unknown osName "solaris", expect linux, windows or macOS`,
+		"execution.os[3]: target of solaris/amd64 is empty",
+		"execution.os[5]: darwin/arm64 is declared more than once",
+		"execution.os: 2 entries have defaultFlag, expect at most one",
+	})
+	assertProblems(t, messages(l, levelWarning), []string{
+		`execution.os[2]: windows target "app" has no .exe suffix`,
+	})
+}
+
+func TestLintTaskInvalid(t *testing.T) {
+	tests := map[string]string{
+		"syntax":       "{\n  \"atomCode\": \"demo\",\n  \"input\": {,}\n}",
+		"no execution": `{"atomCode": ""}`,
+		"no target":    `{"atomCode": "demo", "execution": {"language": "golang"}}`,
+	}
+	want := map[string][]string{
+		"no execution": {"atomCode is empty", "execution is missing"},
+		"no target":    {"execution.target is empty and execution.os is not declared"},
+	}
+	for name, data := range tests {
+		t.Run(name, func(t *testing.T) {
+			file, _ := lintFixture(t, data, "package demo\n")
+			l := new(linter)
+			tk := l.lintTask(file)
+			if name == "syntax" {
+				if tk != nil || len(l.problems) != 1 || !strings.Contains(l.problems[0].message, "invalid json at line 3") {
+					t.Errorf("lintTask() = %v, problems %v", tk, messages(l, levelError))
+				}
+				return
+			}
+			assertProblems(t, messages(l, levelError), want[name])
+		})
+	}
+
+	l := new(linter)
+	if l.lintTask(filepath.Join(t.TempDir(), "missing.json")) != nil || l.errors() != 1 {
+		t.Errorf("lintTask() of a missing file should report one error")
+	}
+}
+
+func TestLintOutputUsage(t *testing.T) {
+	file, src := lintFixture(t, lintTaskJSON, lintSource)
+	l := new(linter)
+	tk := l.lintTask(file)
+	l.problems = nil
+
+	l.lintOutputUsage(tk, file, src)
+	assertProblems(t, messages(l, levelError), []string{
+		`output "extra" is not declared in task.json`,
+		`output "pkg" is string in code but artifact in task.json`,
+		`output "plain" is artifact in code but string in task.json`,
+	})
+	assertProblems(t, messages(l, levelWarning), []string{
+		"output odd is declared but never set in code",
+		"output unused is declared but never set in code",
+	})
+	for _, p := range l.problems {
+		if p.level == levelError && !strings.HasPrefix(p.file, filepath.Join(src, "main.go")+":") {
+			t.Errorf("problem position = %s, want main.go:line:column", p.file)
+		}
+	}
+
+	// 输出名称不是常量时不再提示未使用的输出
+	file, src = lintFixture(t, lintTaskJSON, lintSource+"\nfunc dyn(name string) { api.SetStringOutput(name, \"x\") }\n")
+	l = new(linter)
+	tk = l.lintTask(file)
+	l.problems = nil
+	l.lintOutputUsage(tk, file, src)
+	if got := messages(l, levelWarning); len(got) != 0 {
+		t.Errorf("warnings with dynamic output names = %v, want none", got)
+	}
+}
+
+func TestScanOutputsTypes(t *testing.T) {
+	_, src := lintFixture(t, lintTaskJSON, `package demo
+
+func run() {
+	api.SetStringOutput("s", "x")
+	api.AddArtifactOutput("a", "f")
+	ctx.AddReportOutput("r", nil)
+	api.SetJSONOutput("j", nil)
+	api.GetJSONOutput[int]("ignored")
+	api.AddOutputData("d", api.NewThirdpartyReportData("r", "http://x"))
+	api.AddOutputData("u", data)
+}
+`)
+	usages, dynamic, err := scanOutputs(src)
+	if err != nil || dynamic {
+		t.Fatalf("scanOutputs() dynamic = %v, error = %v", dynamic, err)
+	}
+	want := map[string]string{"s": "string", "a": "artifact", "r": "report", "j": "string", "d": "report", "u": ""}
+	if len(usages) != len(want) {
+		t.Errorf("usages = %d, want %d", len(usages), len(want))
+	}
+	for name, typ := range want {
+		if u, ok := usages[name]; !ok || u.outputType != typ {
+			t.Errorf("usage %s = %+v, want type %q", name, u, typ)
+		}
+	}
+}
+
+func TestLintI18n(t *testing.T) {
+	file, _ := lintFixture(t, lintTaskJSON, lintSource)
+	l := new(linter)
+	tk := l.lintTask(file)
+	l.problems = nil
+
+	dir := t.TempDir()
+	files := map[string]string{
+		"message_zh_CN.properties": "input.mode.label=模式\noutput.ver.description=版本\n",
+		"message_en_US.properties": "input.mode.label=Mode\n",
+		"other.properties":         "",
+	}
+	for name, content := range files {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	l.lintI18n(tk, dir)
+	if len(l.problems) != 1 || l.problems[0].message != "missing key output.ver.description" ||
+		filepath.Base(l.problems[0].file) != "message_en_US.properties" {
+		t.Errorf("problems = %v", messages(l, levelError))
+	}
+
+	l.problems = nil
+	l.lintI18n(tk, filepath.Join(dir, "missing"))
+	if len(l.problems) != 0 {
+		t.Errorf("missing i18n dir should be skipped, got %v", messages(l, levelError))
+	}
+}
+
+func TestPosition(t *testing.T) {
+	data := []byte("ab\ncd\n")
+	tests := []struct {
+		offset    int64
+		line, col int
+	}{
+		{0, 1, 1},
+		{2, 1, 3},
+		{3, 2, 1},
+		{5, 2, 3},
+		{100, 3, 1},
+	}
+	for _, tt := range tests {
+		if line, col := position(data, tt.offset); line != tt.line || col != tt.col {
+			t.Errorf("position(%d) = %d:%d, want %d:%d", tt.offset, line, col, tt.line, tt.col)
+		}
+	}
+}
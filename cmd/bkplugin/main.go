@@ -0,0 +1,89 @@
+/*
+bkplugin 是开发插件时使用的命令行工具
+通过 go install github.com/ci-plugins/golang-plugin-sdk/cmd/bkplugin@latest 命令安装
+
+	bkplugin init -module github.com/xxx/demo demo                 创建插件项目
+	bkplugin run -task task.json -binary ./demo -input name=demo   在本地模拟的构建中执行插件
+	bkplugin package -task task.json -out dist                     交叉编译并打包为可上传的插件包
+	bkplugin gentask -task task.json -input Input -output Output   根据结构体标签生成 task.json 的 input 与 output
+	bkplugin lint -task task.json -src . -i18n i18n                检查 task.json 与代码、国际化文件是否一致
+*/
+package main
+
+import (
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+)
+
+// command 子命令
+type command struct {
+	usage string
+	run   func(args []string) int
+}
+
+var commands = map[string]*command{
+	"gentask": {usage: "根据 go 结构体标签生成或更新 task.json 的 input 与 output", run: gentaskCommand},
+	"init":    {usage: "创建新的插件项目", run: initCommand},
+	"lint":    {usage: "检查 task.json 的组件类型、输入依赖、输出名称、执行入口与国际化 key", run: lintCommand},
+	"package": {usage: "交叉编译并按插件包结构打包，生成 zip 与 sha256 校验和", run: packageCommand},
+	"run":     {usage: "在本地模拟的构建中执行插件，包括后置动作", run: runCommand},
+}
+
+func main() {
+	if len(os.Args) < 2 {
+		usage()
+		exit()
+	}
+	name := os.Args[1]
+	if name == "-h" || name == "--help" || name == "help" {
+		usage()
+		return
+	}
+	cmd, ok := commands[name]
+	if !ok {
+		fmt.Fprintf(os.Stderr, "unknown command %s\n", name)
+		usage()
+		exit()
+	}
+	os.Exit(cmd.run(os.Args[2:]))
+}
+
+func usage() {
+	names := make([]string, 0, len(commands))
+	for name := range commands {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	fmt.Fprintf(os.Stderr, "usage: bkplugin <command> [flags]\n\ncommands:\n")
+	for _, name := range names {
+		fmt.Fprintf(os.Stderr, "  %-10s %s\n", name, commands[name].usage)
+	}
+}
+
+// keyValueFlag 可重复指定的 key=value 参数
+type keyValueFlag map[string]string
+
+func (f keyValueFlag) String() string {
+	pairs := make([]string, 0, len(f))
+	for k, v := range f {
+		pairs = append(pairs, k+"="+v)
+	}
+	sort.Strings(pairs)
+	return strings.Join(pairs, ",")
+}
+
+func (f keyValueFlag) Set(s string) error {
+	i := strings.Index(s, "=")
+	if i <= 0 {
+		return fmt.Errorf("%q is not in key=value form", s)
+	}
+	f[s[:i]] = s[i+1:]
+	return nil
+}
+
+func exit() {
+	os.Exit(1)
+}
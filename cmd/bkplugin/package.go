@@ -0,0 +1,301 @@
+package main
+
+import (
+	"archive/zip"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/ci-plugins/golang-plugin-sdk/task"
+)
+
+// 插件包中的目录
+const (
+	packageFileDir = "file"
+	packageI18nDir = "i18n"
+)
+
+// osNames task.json 中的 osName（小写）对应的 GOOS
+var osNames = map[string]string{
+	"linux":   "linux",
+	"windows": "windows",
+	"macos":   "darwin",
+	"darwin":  "darwin",
+}
+
+// goosOf task.json 中的 osName 对应的 GOOS，不区分大小写，lint 与 package 使用相同的规则
+func goosOf(osName string) (string, bool) {
+	goos, ok := osNames[strings.ToLower(strings.TrimSpace(osName))]
+	return goos, ok
+}
+
+func packageCommand(args []string) int {
+	fs := flag.NewFlagSet("package", flag.ExitOnError)
+	taskFile := fs.String("task", "task.json", "插件的 task.json 路径")
+	src := fs.String("src", ".", "插件 main 包所在目录")
+	i18nDir := fs.String("i18n", "i18n", "国际化文件目录，不存在时忽略")
+	out := fs.String("out", "dist", "输出目录")
+	ldflags := fs.String("ldflags", "-s -w", "go build 的 -ldflags 参数")
+	fs.Parse(args)
+
+	t, err := task.LoadFile(*taskFile)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "load task %s error %s\n", *taskFile, err.Error())
+		return 1
+	}
+	if t.AtomCode == "" {
+		fmt.Fprintf(os.Stderr, "task.json has no atomCode\n")
+		return 1
+	}
+	builds, err := packageBuilds(t)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "%s\n", err.Error())
+		return 1
+	}
+
+	stage, err := ioutil.TempDir("", "bkplugin-package-")
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "create stage dir error %s\n", err.Error())
+		return 1
+	}
+	defer os.RemoveAll(stage)
+
+	for _, b := range builds {
+		output := filepath.Join(stage, packageFileDir, b.file)
+		fmt.Fprintf(os.Stdout, "build %s/%s -> %s\n", b.goos, b.goarch, filepath.ToSlash(filepath.Join(packageFileDir, b.file)))
+		if err = b.build(*src, output, *ldflags); err != nil {
+			fmt.Fprintf(os.Stderr, "build %s/%s error %s\n", b.goos, b.goarch, err.Error())
+			return 1
+		}
+	}
+	if err = copyFile(*taskFile, filepath.Join(stage, "task.json")); err != nil {
+		fmt.Fprintf(os.Stderr, "copy task.json error %s\n", err.Error())
+		return 1
+	}
+	if err = copyI18n(*i18nDir, filepath.Join(stage, packageI18nDir)); err != nil {
+		fmt.Fprintf(os.Stderr, "copy i18n error %s\n", err.Error())
+		return 1
+	}
+	if missing := missingTargets(t, filepath.Join(stage, packageFileDir)); len(missing) > 0 {
+		fmt.Fprintf(os.Stderr, "target not found in package: %s\n", strings.Join(missing, ", "))
+		return 1
+	}
+
+	if err = os.MkdirAll(*out, 0755); err != nil {
+		fmt.Fprintf(os.Stderr, "create output dir error %s\n", err.Error())
+		return 1
+	}
+	zipFile := filepath.Join(*out, t.AtomCode+".zip")
+	if err = zipDir(stage, zipFile); err != nil {
+		fmt.Fprintf(os.Stderr, "zip package error %s\n", err.Error())
+		return 1
+	}
+	checksumFile := filepath.Join(*out, t.AtomCode+".sha256")
+	if err = writeChecksums(stage, zipFile, checksumFile); err != nil {
+		fmt.Fprintf(os.Stderr, "write checksums error %s\n", err.Error())
+		return 1
+	}
+	fmt.Fprintf(os.Stdout, "package %s\nchecksums %s\n", zipFile, checksumFile)
+	return 0
+}
+
+// targetBuild 一个目标平台的构建
+type targetBuild struct {
+	goos   string
+	goarch string
+	file   string // file 目录下的文件名
+}
+
+// packageBuilds 按 execution.os 确定需要构建的平台，osArch 默认为 amd64
+func packageBuilds(t *task.Task) ([]*targetBuild, error) {
+	if t.Execution == nil || len(t.Execution.Os) == 0 {
+		return nil, errors.New("task.json has no execution.os")
+	}
+	var builds []*targetBuild
+	seen := make(map[string]string)
+	for _, o := range t.Execution.Os {
+		goos, ok := goosOf(o.OsName)
+		if !ok {
+			return nil, fmt.Errorf("unsupported osName %s", o.OsName)
+		}
+		goarch := o.OsArch
+		if goarch == "" {
+			goarch = "amd64"
+		}
+		file := targetFile(o.Target)
+		if file == "" {
+			return nil, fmt.Errorf("target of %s/%s is empty", o.OsName, goarch)
+		}
+		if platform, ok := seen[file]; ok {
+			return nil, fmt.Errorf("target %s is used by both %s and %s/%s", file, platform, goos, goarch)
+		}
+		seen[file] = goos + "/" + goarch
+		builds = append(builds, &targetBuild{goos: goos, goarch: goarch, file: file})
+	}
+	return builds, nil
+}
+
+// targetFile 执行入口对应的文件名，去掉参数与 ./ 前缀
+func targetFile(target string) string {
+	fields := strings.Fields(target)
+	if len(fields) == 0 {
+		return ""
+	}
+	return filepath.ToSlash(filepath.Clean(fields[0]))
+}
+
+func (b *targetBuild) build(src string, output string, ldflags string) error {
+	cmd := exec.Command("go", "build", "-trimpath", "-ldflags", ldflags, "-o", output, ".")
+	cmd.Dir = src
+	cmd.Stdout = os.Stdout
+	cmd.Stderr = os.Stderr
+	cmd.Env = append(os.Environ(), "GOOS="+b.goos, "GOARCH="+b.goarch, "CGO_ENABLED=0")
+	return cmd.Run()
+}
+
+// missingTargets 检查 task.json 引用的执行入口是否都在插件包中
+func missingTargets(t *task.Task, fileDir string) []string {
+	targets := []string{t.Execution.Target}
+	for _, o := range t.Execution.Os {
+		targets = append(targets, o.Target)
+	}
+	var missing []string
+	for _, target := range targets {
+		file := targetFile(target)
+		if file == "" {
+			continue
+		}
+		if _, err := os.Stat(filepath.Join(fileDir, filepath.FromSlash(file))); err != nil {
+			missing = append(missing, target)
+		}
+	}
+	return missing
+}
+
+func copyI18n(src string, dst string) error {
+	files, err := ioutil.ReadDir(src)
+	if os.IsNotExist(err) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	for _, f := range files {
+		if f.IsDir() || !strings.HasSuffix(f.Name(), ".properties") {
+			continue
+		}
+		if err = copyFile(filepath.Join(src, f.Name()), filepath.Join(dst, f.Name())); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func copyFile(src string, dst string) error {
+	data, err := ioutil.ReadFile(src)
+	if err != nil {
+		return err
+	}
+	if err = os.MkdirAll(filepath.Dir(dst), 0755); err != nil {
+		return err
+	}
+	return ioutil.WriteFile(dst, data, 0644)
+}
+
+// packageFiles 目录下的全部文件，使用 / 分隔的相对路径并排序
+func packageFiles(dir string) ([]string, error) {
+	var files []string
+	err := filepath.Walk(dir, func(path string, info os.FileInfo, err error) error {
+		if err != nil || info.IsDir() {
+			return err
+		}
+		rel, err := filepath.Rel(dir, path)
+		if err != nil {
+			return err
+		}
+		files = append(files, filepath.ToSlash(rel))
+		return nil
+	})
+	sort.Strings(files)
+	return files, err
+}
+
+func zipDir(dir string, zipFile string) error {
+	files, err := packageFiles(dir)
+	if err != nil {
+		return err
+	}
+	f, err := os.Create(zipFile)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	now := time.Now()
+	w := zip.NewWriter(f)
+	for _, name := range files {
+		header := &zip.FileHeader{Name: name, Method: zip.Deflate, Modified: now}
+		mode := os.FileMode(0644)
+		if strings.HasPrefix(name, packageFileDir+"/") {
+			mode = 0755
+		}
+		header.SetMode(mode)
+		fw, err := w.CreateHeader(header)
+		if err != nil {
+			return err
+		}
+		data, err := ioutil.ReadFile(filepath.Join(dir, filepath.FromSlash(name)))
+		if err != nil {
+			return err
+		}
+		if _, err = fw.Write(data); err != nil {
+			return err
+		}
+	}
+	return w.Close()
+}
+
+// writeChecksums 写入插件包及包内每个文件的 sha256，格式与 sha256sum 一致
+func writeChecksums(dir string, zipFile string, checksumFile string) error {
+	files, err := packageFiles(dir)
+	if err != nil {
+		return err
+	}
+	var lines []string
+	sum, err := sha256File(zipFile)
+	if err != nil {
+		return err
+	}
+	lines = append(lines, sum+"  "+filepath.Base(zipFile))
+	for _, name := range files {
+		sum, err := sha256File(filepath.Join(dir, filepath.FromSlash(name)))
+		if err != nil {
+			return err
+		}
+		lines = append(lines, sum+"  "+name)
+	}
+	return ioutil.WriteFile(checksumFile, []byte(strings.Join(lines, "\n")+"\n"), 0644)
+}
+
+func sha256File(path string) (string, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return "", err
+	}
+	defer f.Close()
+	h := sha256.New()
+	if _, err = io.Copy(h, f); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
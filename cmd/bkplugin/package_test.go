@@ -0,0 +1,37 @@
+package main
+
+import (
+	"testing"
+
+	"github.com/ci-plugins/golang-plugin-sdk/task"
+)
+
+func TestGoosOf(t *testing.T) {
+	tests := map[string]string{"linux": "linux", "Windows": "windows", "macOS": "darwin", "darwin": "darwin", " MACOS ": "darwin"}
+	for osName, want := range tests {
+		if got, ok := goosOf(osName); !ok || got != want {
+			t.Errorf("goosOf(%q) = %q, %v, want %q", osName, got, ok, want)
+		}
+	}
+	if _, ok := goosOf("solaris"); ok {
+		t.Errorf("goosOf(solaris) should not be supported")
+	}
+}
+
+// TestPackageBuildsLintClean lint 不报错的 execution.os 都能打包
+func TestPackageBuildsLintClean(t *testing.T) {
+	tk := &task.Task{AtomCode: "demo", Execution: &task.Execution{Language: "golang", Os: []*task.OsTarget{
+		{OsName: "linux", Target: "./app"},
+		{OsName: "Windows", Target: "app.exe"},
+		{OsName: "macOS", OsArch: "arm64", Target: "./app_mac", DefaultFlag: true},
+	}}}
+	l := new(linter)
+	l.lintExecution(tk, "task.json")
+	if len(l.problems) != 0 {
+		t.Fatalf("lint problems = %v", messages(l, levelError))
+	}
+	builds, err := packageBuilds(tk)
+	if err != nil || len(builds) != 3 || builds[1].goos != "windows" || builds[2].goos != "darwin" || builds[2].goarch != "arm64" {
+		t.Errorf("packageBuilds() = %v, %v", builds, err)
+	}
+}
@@ -0,0 +1,459 @@
+package main
+
+import (
+	"bufio"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"os"
+	"os/exec"
+	"os/user"
+	"path/filepath"
+	"runtime"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/ci-plugins/golang-plugin-sdk/api"
+	"github.com/ci-plugins/golang-plugin-sdk/task"
+)
+
+// 本地模拟构建使用的项目与凭证
+const (
+	runProjectId = "local-project"
+	runAgentId   = "local-agent"
+	runSecretKey = "local-secret"
+)
+
+func runCommand(args []string) int {
+	fs := flag.NewFlagSet("run", flag.ExitOnError)
+	taskFile := fs.String("task", "task.json", "插件的 task.json 路径")
+	binary := fs.String("binary", "", "插件可执行文件，默认取 task.json 中当前系统的 target")
+	dataDir := fs.String("data-dir", "", "数据目录，默认创建临时目录")
+	workspace := fs.String("workspace", "", "工作空间，默认为当前目录")
+	gateway := fs.String("gateway", "", "蓝盾网关地址，为空时启动本地网关，远程调用返回空数据")
+	noPrompt := fs.Bool("no-prompt", false, "缺少必填参数时直接报错而不提示输入")
+	skipPost := fs.Bool("skip-post", false, "不执行后置动作")
+	inputs := make(keyValueFlag)
+	fs.Var(inputs, "input", "输入参数，格式为 key=value，可重复指定")
+	fs.Parse(args)
+
+	t, err := task.LoadFile(*taskFile)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "load task %s error %s\n", *taskFile, err.Error())
+		return 1
+	}
+
+	if *binary == "" {
+		if *binary, err = taskBinary(t, filepath.Dir(*taskFile)); err != nil {
+			fmt.Fprintf(os.Stderr, "%s, use -binary to specify the plugin binary\n", err.Error())
+			return 1
+		}
+	}
+	if *binary, err = filepath.Abs(*binary); err != nil {
+		fmt.Fprintf(os.Stderr, "binary path abs error %s\n", err.Error())
+		return 1
+	}
+	if *taskFile, err = filepath.Abs(*taskFile); err != nil {
+		fmt.Fprintf(os.Stderr, "task path abs error %s\n", err.Error())
+		return 1
+	}
+
+	if *dataDir == "" {
+		if *dataDir, err = ioutil.TempDir("", "bkplugin-"); err != nil {
+			fmt.Fprintf(os.Stderr, "create data dir error %s\n", err.Error())
+			return 1
+		}
+	} else if err = os.MkdirAll(*dataDir, 0755); err != nil {
+		fmt.Fprintf(os.Stderr, "create data dir error %s\n", err.Error())
+		return 1
+	}
+	if *workspace == "" {
+		*workspace, _ = os.Getwd()
+	}
+	if *workspace, err = filepath.Abs(*workspace); err != nil {
+		fmt.Fprintf(os.Stderr, "workspace path abs error %s\n", err.Error())
+		return 1
+	}
+	if *dataDir, err = filepath.Abs(*dataDir); err != nil {
+		fmt.Fprintf(os.Stderr, "data dir path abs error %s\n", err.Error())
+		return 1
+	}
+	fmt.Fprintf(os.Stdout, "data dir %s\n", *dataDir)
+
+	values, err := collectInputs(t, inputs, !*noPrompt && isTerminal(os.Stdin))
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "%s\n", err.Error())
+		return 1
+	}
+
+	if *gateway == "" {
+		stop, addr, err := startStubGateway()
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "start stub gateway error %s\n", err.Error())
+			return 1
+		}
+		defer stop()
+		*gateway = addr
+	}
+
+	b := newSyntheticBuild(t, *workspace)
+	sdkEnv := b.sdkEnv(*gateway)
+	input := b.input(values)
+	if err = writeJSON(filepath.Join(*dataDir, ".sdk.json"), sdkEnv); err != nil {
+		fmt.Fprintf(os.Stderr, "write .sdk.json error %s\n", err.Error())
+		return 1
+	}
+	if err = writeJSON(filepath.Join(*dataDir, "input.json"), input); err != nil {
+		fmt.Fprintf(os.Stderr, "write input.json error %s\n", err.Error())
+		return 1
+	}
+
+	p := &pluginProcess{binary: *binary, taskFile: *taskFile, dataDir: *dataDir, workspace: *workspace}
+	output, err := p.run("output.json")
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "run plugin error %s\n", err.Error())
+		return 1
+	}
+	printOutput(os.Stdout, "main", output)
+	code := statusCode(output.Status)
+
+	post := t.Execution.GetPost()
+	if post != nil && !*skipPost && shouldRunPost(post.PostCondition, output.Status) {
+		postOutput, err := p.run("output_post.json", "-postAction", post.PostEntryParam)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "run post action error %s\n", err.Error())
+			return 1
+		}
+		printOutput(os.Stdout, "post action "+post.PostEntryParam, postOutput)
+		if code == 0 {
+			code = statusCode(postOutput.Status)
+		}
+	}
+	return code
+}
+
+// taskBinary 取 task.json 中当前系统的执行入口，相对路径基于 task.json 所在目录
+func taskBinary(t *task.Task, dir string) (string, error) {
+	if t.Execution == nil {
+		return "", errors.New("task.json has no execution")
+	}
+	target := t.Execution.Target
+	for _, o := range t.Execution.Os {
+		if strings.EqualFold(o.OsName, goosName()) && (o.OsArch == "" || o.OsArch == runtime.GOARCH) {
+			target = o.Target
+			break
+		}
+	}
+	fields := strings.Fields(target)
+	if len(fields) == 0 {
+		return "", fmt.Errorf("task.json has no target for %s", goosName())
+	}
+	binary := fields[0]
+	if !filepath.IsAbs(binary) {
+		binary = filepath.Join(dir, binary)
+	}
+	return binary, nil
+}
+
+// goosName 当前系统在 task.json 中的名称
+func goosName() string {
+	if runtime.GOOS == "darwin" {
+		return "macOS"
+	}
+	return runtime.GOOS
+}
+
+// collectInputs 合并命令行参数与 task.json 中的默认值，缺少必填参数时提示输入
+func collectInputs(t *task.Task, given map[string]string, prompt bool) (map[string]interface{}, error) {
+	values := make(map[string]interface{})
+	for _, input := range t.Input {
+		if input.Default != nil {
+			values[input.Name] = input.Default
+		}
+	}
+	for k, v := range given {
+		values[k] = v
+	}
+
+	reader := bufio.NewReader(os.Stdin)
+	var missing []string
+	for _, input := range t.Input {
+		if !input.Required || !input.Rely.Satisfied(values) || !isEmpty(values[input.Name]) {
+			continue
+		}
+		if !prompt {
+			missing = append(missing, input.Name)
+			continue
+		}
+		value, err := promptInput(reader, input)
+		if err != nil {
+			return nil, err
+		}
+		values[input.Name] = value
+	}
+	if len(missing) > 0 {
+		return nil, fmt.Errorf("missing required input: %s", strings.Join(missing, ", "))
+	}
+	return values, nil
+}
+
+func promptInput(reader *bufio.Reader, input *task.Input) (string, error) {
+	label := input.Name
+	if input.Label != "" {
+		label = fmt.Sprintf("%s(%s)", input.Name, input.Label)
+	}
+	for _, c := range input.Choices() {
+		fmt.Fprintf(os.Stdout, "  %s: %s\n", c.Key(), c.Text())
+	}
+	for {
+		fmt.Fprintf(os.Stdout, "%s: ", label)
+		line, err := reader.ReadString('\n')
+		line = strings.TrimSpace(line)
+		if line != "" {
+			return line, nil
+		}
+		if err == io.EOF {
+			return "", fmt.Errorf("input %s is required", input.Name)
+		}
+		if err != nil {
+			return "", err
+		}
+	}
+}
+
+func isEmpty(v interface{}) bool {
+	switch value := v.(type) {
+	case nil:
+		return true
+	case string:
+		return strings.TrimSpace(value) == ""
+	}
+	return false
+}
+
+func isTerminal(f *os.File) bool {
+	info, err := f.Stat()
+	return err == nil && info.Mode()&os.ModeCharDevice != 0
+}
+
+// syntheticBuild 模拟的构建信息
+type syntheticBuild struct {
+	task       *task.Task
+	workspace  string
+	pipelineId string
+	buildId    string
+	taskId     string
+	user       string
+	startTime  time.Time
+}
+
+func newSyntheticBuild(t *task.Task, workspace string) *syntheticBuild {
+	userName := "local"
+	if u, err := user.Current(); err == nil && u.Username != "" {
+		userName = u.Username
+	}
+	return &syntheticBuild{
+		task:       t,
+		workspace:  workspace,
+		pipelineId: "p-" + randomId(),
+		buildId:    "b-" + randomId(),
+		taskId:     "e-" + randomId(),
+		user:       userName,
+		startTime:  time.Now(),
+	}
+}
+
+func (b *syntheticBuild) sdkEnv(gateway string) *api.SdkEnv {
+	return &api.SdkEnv{
+		BuildType: api.BuildTypeWorker,
+		ProjectId: runProjectId,
+		AgentId:   runAgentId,
+		SecretKey: runSecretKey,
+		Gateway:   gateway,
+		BuildId:   b.buildId,
+		VmSeqId:   "1",
+		TaskId:    b.taskId,
+	}
+}
+
+func (b *syntheticBuild) input(values map[string]interface{}) map[string]interface{} {
+	input := map[string]interface{}{
+		"BK_CI_PROJECT_NAME":         runProjectId,
+		"BK_CI_PROJECT_NAME_CN":      runProjectId,
+		"BK_CI_PIPELINE_ID":          b.pipelineId,
+		"BK_CI_PIPELINE_NAME":        "local pipeline",
+		"BK_CI_PIPELINE_VERSION":     "1",
+		"BK_CI_BUILD_ID":             b.buildId,
+		"BK_CI_BUILD_NUM":            "1",
+		"BK_CI_BUILD_START_TIME":     strconv.FormatInt(b.startTime.UnixNano()/int64(time.Millisecond), 10),
+		"BK_CI_START_TYPE":           "MANUAL",
+		"BK_CI_START_USER_ID":        b.user,
+		"BK_CI_START_USER_NAME":      b.user,
+		"BK_CI_PIPELINE_CREATE_USER": b.user,
+		"BK_CI_PIPELINE_UPDATE_USER": b.user,
+		"BK_CI_BUILD_TASK_ID":        b.taskId,
+		"BK_CI_ATOM_CODE":            b.task.AtomCode,
+		"BK_CI_ATOM_NAME":            b.task.AtomCode,
+		"BK_CI_ATOM_VERSION":         "1.0.0",
+		"BK_CI_TASK_NAME":            b.task.AtomCode,
+		"BK_CI_STEP_ID":              "",
+		"bkWorkspace":                b.workspace,
+		"testVersionFlag":            "Y",
+	}
+	for k, v := range values {
+		input[k] = v
+	}
+	return input
+}
+
+// startStubGateway 启动本地网关，所有请求返回空数据
+func startStubGateway() (func(), string, error) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		return nil, "", err
+	}
+	server := &http.Server{Handler: http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		fmt.Fprintf(os.Stderr, "stub gateway: %s %s\n", req.Method, req.URL.Path)
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte(`{"status":0,"data":null}`))
+	})}
+	go server.Serve(listener)
+	return func() { server.Close() }, "http://" + listener.Addr().String(), nil
+}
+
+func randomId() string {
+	buf := make([]byte, 8)
+	rand.Read(buf)
+	return hex.EncodeToString(buf)
+}
+
+// pluginProcess 在数据目录中执行插件
+type pluginProcess struct {
+	binary    string
+	taskFile  string
+	dataDir   string
+	workspace string
+}
+
+// run 执行插件并读取输出文件
+func (p *pluginProcess) run(outputFile string, args ...string) (*api.AtomOutput, error) {
+	outputPath := filepath.Join(p.dataDir, outputFile)
+	os.Remove(outputPath)
+
+	cmd := exec.Command(p.binary, args...)
+	cmd.Dir = p.workspace
+	cmd.Stdin = os.Stdin
+	cmd.Stdout = os.Stdout
+	cmd.Stderr = os.Stderr
+	cmd.Env = append(os.Environ(),
+		api.DataDirEnv+"="+p.dataDir,
+		api.InputFileEnv+"=input.json",
+		api.OutputFileEnv+"="+outputFile,
+		api.TaskFileEnv+"="+p.taskFile,
+	)
+
+	runErr := cmd.Run()
+	data, err := ioutil.ReadFile(outputPath)
+	if err != nil {
+		if runErr != nil {
+			return nil, runErr
+		}
+		return nil, fmt.Errorf("read %s error %s", outputFile, err.Error())
+	}
+	output := new(api.AtomOutput)
+	if err = json.Unmarshal(data, output); err != nil {
+		return nil, fmt.Errorf("parse %s error %s", outputFile, err.Error())
+	}
+	return output, nil
+}
+
+// shouldRunPost 按 postCondition 判断是否执行后置动作
+func shouldRunPost(condition string, status api.Status) bool {
+	switch strings.TrimSuffix(strings.TrimSpace(condition), "()") {
+	case "success":
+		return status == api.StatusSuccess
+	case "failure":
+		return status != api.StatusSuccess
+	default:
+		return true
+	}
+}
+
+func statusCode(status api.Status) int {
+	if status == api.StatusSuccess {
+		return 0
+	}
+	return 1
+}
+
+// printOutput 打印插件输出、构件与质量红线数据
+func printOutput(w io.Writer, phase string, output *api.AtomOutput) {
+	fmt.Fprintf(w, "\n==== %s ====\n", phase)
+	fmt.Fprintf(w, "status:  %s\n", output.Status)
+	fmt.Fprintf(w, "message: %s\n", output.Message)
+	if output.ErrorCode != 0 {
+		fmt.Fprintf(w, "error:   code %d, type %d\n", output.ErrorCode, output.ErrorType)
+	}
+	if output.PlatformCode != "" {
+		fmt.Fprintf(w, "platform: %s %d\n", output.PlatformCode, output.PlatformErrorCode)
+	}
+
+	keys := make([]string, 0, len(output.Data))
+	for k := range output.Data {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	var variables, artifacts, reports []string
+	for _, k := range keys {
+		data, _ := output.Data[k].(map[string]interface{})
+		switch api.DataType(fmt.Sprint(data["type"])) {
+		case api.DataTypeArtifact:
+			files, _ := json.Marshal(data["value"])
+			artifacts = append(artifacts, fmt.Sprintf("  %s: %s", k, files))
+		case api.DataTypeReport:
+			reports = append(reports, fmt.Sprintf("  %s: %v %v%v", k, data["label"], data["path"], data["url"]))
+		default:
+			variables = append(variables, fmt.Sprintf("  %s = %v", k, data["value"]))
+		}
+	}
+	printSection(w, "outputs", variables)
+	printSection(w, "artifacts", artifacts)
+	printSection(w, "reports", reports)
+
+	keys = keys[:0]
+	for k := range output.QualityData {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	var quality []string
+	for _, k := range keys {
+		if q := output.QualityData[k]; q != nil {
+			quality = append(quality, fmt.Sprintf("  %s = %s", k, q.Value))
+		}
+	}
+	printSection(w, "quality data", quality)
+}
+
+func printSection(w io.Writer, title string, lines []string) {
+	if len(lines) == 0 {
+		return
+	}
+	fmt.Fprintf(w, "%s:\n%s\n", title, strings.Join(lines, "\n"))
+}
+
+func writeJSON(path string, v interface{}) error {
+	data, err := json.MarshalIndent(v, "", "  ")
+	if err != nil {
+		return err
+	}
+	return ioutil.WriteFile(path, data, 0644)
+}
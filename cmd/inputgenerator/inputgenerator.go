@@ -0,0 +1,337 @@
+/*
+inputgenerator 根据插件 task.json 中的 input 生成对应的 go 结构体，避免手动维护结构体与 task.json 不一致
+通过 go install github.com/ci-plugins/golang-plugin-sdk/cmd/inputgenerator@latest 命令安装
+在插件代码中加入 //go:generate inputgenerator [task.json 路径] [生成的 go 文件路径]
+同时在项目根目录下 go generate . 即可生成结构体
+
+生成的结构体带有 json 与 bk 标签，默认字段均为 string，可直接使用 LoadInputParam 加载
+使用 -typed 时复选框生成 bool、多选生成 []string，需使用 Bind 加载
+Default<结构体名> 返回填充了 task.json 默认值的结构体，选项类输入会生成对应的常量
+*/
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"go/format"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"unicode"
+
+	"github.com/ci-plugins/golang-plugin-sdk/task"
+)
+
+func main() {
+	typeName := flag.String("type", "Input", "生成的结构体名称")
+	pkgName := flag.String("package", "", "生成的包名，默认取 go generate 提供的 GOPACKAGE，再默认为 main")
+	typed := flag.Bool("typed", false, "复选框与多选使用 bool 与 []string，需使用 Bind 加载")
+	flag.Parse()
+
+	fmt.Fprintf(os.Stdout, "start running inputgenerator...\n")
+	if flag.NArg() < 2 {
+		fmt.Fprintf(os.Stderr, "args not enough, need task.json path and output path\n")
+		exit()
+	}
+	taskFile := flag.Arg(0)
+	outputName := flag.Arg(1)
+	if !strings.HasSuffix(filepath.Base(outputName), ".go") {
+		fmt.Fprintf(os.Stderr, "output file must go file\n")
+		exit()
+	}
+	if *pkgName == "" {
+		*pkgName = os.Getenv("GOPACKAGE")
+	}
+	if *pkgName == "" {
+		*pkgName = "main"
+	}
+
+	t, err := task.LoadFile(taskFile)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "load task %s error %s\n", taskFile, err.Error())
+		exit()
+	}
+	fmt.Fprintf(os.Stdout, "task file %s, %d inputs\n", taskFile, len(t.Input))
+
+	g := &Generator{typeName: *typeName, typed: *typed}
+	src, err := g.generate(*pkgName, t)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "%s\n", err.Error())
+		exit()
+	}
+	if err = os.MkdirAll(filepath.Dir(outputName), os.ModePerm); err != nil {
+		fmt.Fprintf(os.Stderr, "create output dir: %s", err)
+		exit()
+	}
+	if err = os.WriteFile(outputName, src, 0644); err != nil {
+		fmt.Fprintf(os.Stderr, "writing output: %s", err)
+		exit()
+	}
+	fmt.Fprintf(os.Stdout, "output file path %s\n", outputName)
+}
+
+// field 输入对应的结构体字段
+type field struct {
+	input  *task.Input
+	name   string
+	goType string
+}
+
+// Generator 保存生成的代码，主要用来缓冲 format.Source 的输出
+type Generator struct {
+	buf      bytes.Buffer
+	typeName string
+	typed    bool
+	// idents 已生成的包级标识符，避免不同输入的常量重名
+	idents map[string]bool
+}
+
+// Printf 输出代码
+func (g *Generator) Printf(format string, args ...interface{}) {
+	fmt.Fprintf(&g.buf, format, args...)
+}
+
+// generate 生成格式化后的代码，生成的代码无法格式化时返回错误
+func (g *Generator) generate(pkgName string, t *task.Task) ([]byte, error) {
+	g.Printf("// Code generated by \"inputgenerator\"; DO NOT EDIT.\n")
+	g.Printf("\n")
+	g.Printf("package %s\n", pkgName)
+	g.Printf("\n")
+	fields, err := g.fields(t.Input)
+	if err != nil {
+		return nil, err
+	}
+	g.idents = map[string]bool{g.typeName: true, "Default" + g.typeName: true}
+	g.generateStruct(fields)
+	g.generateConsts(fields)
+	g.generateDefault(fields)
+
+	src, err := format.Source(g.buf.Bytes())
+	if err != nil {
+		return nil, fmt.Errorf("format output error %s", err.Error())
+	}
+	return src, nil
+}
+
+// fields 按声明顺序生成字段，跳过提示类组件
+func (g *Generator) fields(inputs task.Inputs) ([]*field, error) {
+	var fields []*field
+	names := make(map[string]string)
+	for _, input := range inputs {
+		if input.Type == task.TypeTips {
+			continue
+		}
+		name := exportedName(input.Name)
+		if name == "" {
+			return nil, fmt.Errorf("input %q can not be converted to a go field name", input.Name)
+		}
+		if other, ok := names[name]; ok {
+			return nil, fmt.Errorf("input %s and %s both map to field %s", other, input.Name, name)
+		}
+		names[name] = input.Name
+		fields = append(fields, &field{input: input, name: name, goType: g.goType(input)})
+	}
+	return fields, nil
+}
+
+func (g *Generator) goType(input *task.Input) string {
+	if !g.typed {
+		return "string"
+	}
+	switch {
+	case input.Type == task.TypeCheckbox:
+		return "bool"
+	case input.Type == task.TypeCheckboxList || input.MultiSelect:
+		return "[]string"
+	default:
+		return "string"
+	}
+}
+
+func (g *Generator) generateStruct(fields []*field) {
+	g.Printf("// %s 插件输入参数，由 task.json 生成\n", g.typeName)
+	g.Printf("type %s struct {\n", g.typeName)
+	for _, f := range fields {
+		for _, line := range fieldComment(f) {
+			g.Printf("// %s\n", line)
+		}
+		g.Printf("%s %s `%s`\n", f.name, f.goType, fieldTag(f))
+	}
+	g.Printf("}\n\n")
+}
+
+func fieldComment(f *field) []string {
+	var lines []string
+	title := strings.TrimSpace(f.input.Label)
+	if title == "" {
+		title = f.input.Name
+	}
+	lines = append(lines, f.name+" "+oneLine(title))
+	if desc := strings.TrimSpace(f.input.Desc); desc != "" {
+		for _, l := range strings.Split(desc, "\n") {
+			if l = strings.TrimSpace(l); l != "" {
+				lines = append(lines, l)
+			}
+		}
+	}
+	return lines
+}
+
+// fieldTag 生成字段标签，标签值使用 strconv.Quote 转义
+// 默认值包含反引号时标签无法作为原始字符串，此时不生成 default，由 Default 函数提供默认值
+func fieldTag(f *field) string {
+	bk := []string{f.input.Name}
+	if f.input.Required {
+		bk = append(bk, "required")
+	}
+	tag := buildTag(f.input.Name, bk)
+	if def, ok := defaultText(f.input.Default); ok {
+		withDefault := buildTag(f.input.Name, append(bk, "default="+def))
+		if !strings.Contains(withDefault, "`") {
+			tag = withDefault
+		}
+	}
+	return tag
+}
+
+func buildTag(name string, bk []string) string {
+	return "json:" + strconv.Quote(name) + " bk:" + strconv.Quote(strings.Join(bk, ","))
+}
+
+// generateConsts 为选项类输入生成常量
+func (g *Generator) generateConsts(fields []*field) {
+	for _, f := range fields {
+		choices := f.input.Choices()
+		if len(choices) == 0 {
+			continue
+		}
+		g.Printf("// %s 的可选值\n", f.name)
+		g.Printf("const (\n")
+		for i, c := range choices {
+			name := f.name + camelName(c.Key())
+			if name == f.name || g.idents[name] {
+				name = fmt.Sprintf("%sOption%d", f.name, i+1)
+			}
+			for n := 2; g.idents[name]; n++ {
+				name = fmt.Sprintf("%sOption%d_%d", f.name, i+1, n)
+			}
+			g.idents[name] = true
+			if text := oneLine(c.Text()); text != "" {
+				g.Printf("%s = %s // %s\n", name, strconv.Quote(c.Key()), text)
+			} else {
+				g.Printf("%s = %s\n", name, strconv.Quote(c.Key()))
+			}
+		}
+		g.Printf(")\n\n")
+	}
+}
+
+// generateDefault 生成返回默认值的函数，在 LoadInputParam 前使用可保留未传入参数的默认值
+func (g *Generator) generateDefault(fields []*field) {
+	g.Printf("// Default%s 获取填充了 task.json 默认值的输入参数\n", g.typeName)
+	g.Printf("func Default%s() *%s {\n", g.typeName, g.typeName)
+	g.Printf("return &%s{\n", g.typeName)
+	for _, f := range fields {
+		if literal, ok := defaultLiteral(f); ok {
+			g.Printf("%s: %s,\n", f.name, literal)
+		}
+	}
+	g.Printf("}\n")
+	g.Printf("}\n")
+}
+
+// defaultText 默认值的文本形式，数组与对象使用 JSON
+func defaultText(v interface{}) (string, bool) {
+	switch value := v.(type) {
+	case nil:
+		return "", false
+	case string:
+		return value, value != ""
+	case bool, float64:
+		return fmt.Sprint(value), true
+	default:
+		data, err := json.Marshal(value)
+		if err != nil {
+			return "", false
+		}
+		return string(data), true
+	}
+}
+
+func defaultLiteral(f *field) (string, bool) {
+	switch f.goType {
+	case "bool":
+		text, ok := defaultText(f.input.Default)
+		if !ok {
+			return "", false
+		}
+		b, err := strconv.ParseBool(text)
+		if err != nil {
+			return "", false
+		}
+		return strconv.FormatBool(b), true
+	case "[]string":
+		var items []string
+		switch value := f.input.Default.(type) {
+		case []interface{}:
+			for _, item := range value {
+				text, _ := defaultText(item)
+				items = append(items, strconv.Quote(text))
+			}
+		case string:
+			for _, item := range strings.Split(value, ",") {
+				if item = strings.TrimSpace(item); item != "" {
+					items = append(items, strconv.Quote(item))
+				}
+			}
+		}
+		if len(items) == 0 {
+			return "", false
+		}
+		return "[]string{" + strings.Join(items, ", ") + "}", true
+	default:
+		text, ok := defaultText(f.input.Default)
+		if !ok {
+			return "", false
+		}
+		return strconv.Quote(text), true
+	}
+}
+
+// exportedName 将输入名称转换为导出的驼峰命名，如 repo_url、repo-url 转换为 RepoUrl
+func exportedName(s string) string {
+	name := camelName(s)
+	if name != "" && !unicode.IsLetter([]rune(name)[0]) {
+		name = "X" + name
+	}
+	return name
+}
+
+// camelName 去掉字母与数字以外的字符，并将每段首字母大写
+func camelName(s string) string {
+	var b strings.Builder
+	upper := true
+	for _, r := range s {
+		if !unicode.IsLetter(r) && !unicode.IsDigit(r) {
+			upper = true
+			continue
+		}
+		if upper {
+			r = unicode.ToUpper(r)
+			upper = false
+		}
+		b.WriteRune(r)
+	}
+	return b.String()
+}
+
+func oneLine(s string) string {
+	return strings.Join(strings.Fields(s), " ")
+}
+
+func exit() {
+	os.Exit(1)
+}
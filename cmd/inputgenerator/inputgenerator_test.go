@@ -0,0 +1,179 @@
+package main
+
+import (
+	"go/ast"
+	"go/parser"
+	"go/token"
+	"go/types"
+	"reflect"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/ci-plugins/golang-plugin-sdk/task"
+)
+
+const generatorTaskJSON = `{
+	"atomCode": "demo",
+	"input": {
+		"repo_url": {"label": "仓库地址", "type": "vuex-input", "required": true, "desc": "第一行\n第二行"},
+		"tool_dir": {"type": "vuex-input", "default": "C:\\tools\\bin"},
+		"quoted": {"type": "vuex-input", "default": "say \"hi\", bye"},
+		"script": {"type": "atom-ace-editor", "default": "echo ` + "`date`" + `\nexit 0"},
+		"mode": {"type": "selector", "default": "fast", "options": [{"id": "fast", "name": "Fast"}, {"id": "1", "name": "One"}]},
+		"enabled": {"type": "atom-checkbox", "default": true},
+		"langs": {"type": "atom-checkbox-list", "default": ["go", "java"], "list": [{"id": "go"}, {"id": "java"}]},
+		"tip": {"type": "tips"}
+	}
+}`
+
+// generateFields 生成代码并解析出结构体字段的标签与 Default 函数中的默认值
+func generateFields(t *testing.T, typed bool) (map[string]reflect.StructTag, map[string]string, string) {
+	t.Helper()
+	tk, err := task.Parse([]byte(generatorTaskJSON))
+	if err != nil {
+		t.Fatalf("parse task: %v", err)
+	}
+	g := &Generator{typeName: "Input", typed: typed}
+	src, err := g.generate("main", tk)
+	if err != nil {
+		t.Fatalf("generate() error = %v", err)
+	}
+	f, err := parser.ParseFile(token.NewFileSet(), "input.go", src, 0)
+	if err != nil {
+		t.Fatalf("generated code does not parse: %v\n%s", err, src)
+	}
+
+	tags := make(map[string]reflect.StructTag)
+	defaults := make(map[string]string)
+	ast.Inspect(f, func(n ast.Node) bool {
+		switch n := n.(type) {
+		case *ast.StructType:
+			for _, field := range n.Fields.List {
+				tag, err := strconv.Unquote(field.Tag.Value)
+				if err != nil {
+					t.Fatalf("unquote tag %s: %v", field.Tag.Value, err)
+				}
+				tags[field.Names[0].Name] = reflect.StructTag(tag)
+			}
+		case *ast.KeyValueExpr:
+			if lit, ok := n.Value.(*ast.BasicLit); ok && lit.Kind == token.STRING {
+				value, _ := strconv.Unquote(lit.Value)
+				defaults[n.Key.(*ast.Ident).Name] = value
+			}
+		}
+		return true
+	})
+	return tags, defaults, string(src)
+}
+
+func TestFieldTags(t *testing.T) {
+	tags, defaults, src := generateFields(t, false)
+
+	tests := []struct {
+		field string
+		json  string
+		bk    string
+	}{
+		{"RepoUrl", "repo_url", "repo_url,required"},
+		{"ToolDir", "tool_dir", `tool_dir,default=C:\tools\bin`},
+		{"Quoted", "quoted", `quoted,default=say "hi", bye`},
+		{"Script", "script", "script"},
+		{"Mode", "mode", "mode,default=fast"},
+		{"Enabled", "enabled", "enabled,default=true"},
+		{"Langs", "langs", `langs,default=["go","java"]`},
+	}
+	for _, tt := range tests {
+		tag, ok := tags[tt.field]
+		if !ok {
+			t.Errorf("field %s not generated:\n%s", tt.field, src)
+			continue
+		}
+		if got := tag.Get("json"); got != tt.json {
+			t.Errorf("%s json tag = %q, want %q", tt.field, got, tt.json)
+		}
+		if got := tag.Get("bk"); got != tt.bk {
+			t.Errorf("%s bk tag = %q, want %q", tt.field, got, tt.bk)
+		}
+	}
+	if _, ok := tags["Tip"]; ok {
+		t.Errorf("tips input should not generate a field")
+	}
+	// 标签中无法携带的默认值由 Default 函数提供
+	if got, want := defaults["Script"], "echo `date`\nexit 0"; got != want {
+		t.Errorf("DefaultInput Script = %q, want %q", got, want)
+	}
+	if got, want := defaults["ToolDir"], `C:\tools\bin`; got != want {
+		t.Errorf("DefaultInput ToolDir = %q, want %q", got, want)
+	}
+	for _, c := range []string{`ModeFast = "fast"`, `Mode1    = "1"`, "// RepoUrl 仓库地址", "// 第二行"} {
+		if !strings.Contains(src, c) {
+			t.Errorf("generated code missing %q:\n%s", c, src)
+		}
+	}
+}
+
+func TestTypedFields(t *testing.T) {
+	_, _, src := generateFields(t, true)
+	for _, c := range []string{"Enabled bool", "Langs []string", "Enabled: true", `Langs:   []string{"go", "java"}`} {
+		if !strings.Contains(src, c) {
+			t.Errorf("generated code missing %q:\n%s", c, src)
+		}
+	}
+}
+
+func TestExportedName(t *testing.T) {
+	tests := map[string]string{
+		"repo_url": "RepoUrl",
+		"repo-url": "RepoUrl",
+		"repoUrl":  "RepoUrl",
+		"1st":      "X1st",
+		"__":       "",
+	}
+	for in, want := range tests {
+		if got := exportedName(in); got != want {
+			t.Errorf("exportedName(%q) = %q, want %q", in, got, want)
+		}
+	}
+}
+
+func TestFieldNameConflict(t *testing.T) {
+	tk, err := task.Parse([]byte(`{"input": {"repo_url": {"type": "vuex-input"}, "repo-url": {"type": "vuex-input"}}}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	g := &Generator{typeName: "Input"}
+	if _, err = g.generate("main", tk); err == nil {
+		t.Errorf("generate() with conflicting field names error = nil")
+	}
+}
+
+func TestConstNameConflict(t *testing.T) {
+	tk, err := task.Parse([]byte(`{"input": {
+		"a": {"type": "selector", "options": [{"id": "b_c"}, {"id": "x"}]},
+		"a_b": {"type": "selector", "options": [{"id": "c"}]},
+		"default": {"type": "selector", "options": [{"id": "input"}]}
+	}}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	g := &Generator{typeName: "Input"}
+	src, err := g.generate("main", tk)
+	if err != nil {
+		t.Fatalf("generate() error = %v", err)
+	}
+	fset := token.NewFileSet()
+	f, err := parser.ParseFile(fset, "input.go", src, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// 类型检查可发现重复声明的常量
+	if _, err = new(types.Config).Check("main", fset, []*ast.File{f}, nil); err != nil {
+		t.Fatalf("generated code does not compile: %v\n%s", err, src)
+	}
+	for _, c := range []string{`ABC = "b_c"`, `ABOption1 = "c"`, `DefaultOption1 = "input"`} {
+		if !strings.Contains(string(src), c) {
+			t.Errorf("generated code missing %q:\n%s", c, src)
+		}
+	}
+}
@@ -0,0 +1,311 @@
+/*
+Package cassette 录制插件通过 SDK 访问蓝盾后台的 http 请求，并在测试中按顺序回放
+
+录制时会将鉴权请求头以及凭证、oauth 接口返回的数据替换为 REDACTED，录制文件可以直接提交到代码库
+
+	func TestPlugin(t *testing.T) {
+		cassette.Use(t, "testdata/build.json", cassette.ModeAuto)
+		res := sdktest.NewEnv(t).Run(run)
+		...
+	}
+*/
+package cassette
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/ci-plugins/golang-plugin-sdk/api"
+)
+
+// Redacted 脱敏后的内容
+const Redacted = "REDACTED"
+
+// Mode 录制模式
+type Mode int
+
+// 录制模式
+const (
+	ModeReplay Mode = iota // 仅回放，未录制的请求返回错误
+	ModeRecord             // 发起真实请求并录制，覆盖已有的录制文件
+	ModeAuto               // 录制文件存在时回放，否则录制
+)
+
+// DefaultRedactHeaders 默认脱敏的请求头
+var DefaultRedactHeaders = []string{
+	api.AuthHeaderDevopsAgentSecretKey,
+	"Authorization",
+	"Cookie",
+}
+
+// DefaultRedactPaths 默认对响应数据脱敏的接口路径前缀
+var DefaultRedactPaths = []string{
+	"/ticket/api/build/credentials/",
+	"/repository/api/build/oauth/",
+}
+
+// Cassette 录制文件内容
+type Cassette struct {
+	Interactions []*Interaction `json:"interactions"`
+}
+
+// Interaction 一次请求与响应
+type Interaction struct {
+	Request  *Request  `json:"request"`
+	Response *Response `json:"response"`
+}
+
+// Request 录制的请求
+type Request struct {
+	Method  string            `json:"method"`
+	Path    string            `json:"path"`
+	Query   string            `json:"query,omitempty"`
+	Headers map[string]string `json:"headers,omitempty"`
+	Body    string            `json:"body,omitempty"`
+}
+
+// Response 录制的响应
+type Response struct {
+	Status  int               `json:"status"`
+	Headers map[string]string `json:"headers,omitempty"`
+	Body    string            `json:"body,omitempty"`
+}
+
+// Recorder 录制与回放请求的 http.RoundTripper
+type Recorder struct {
+	RedactHeaders []string          // 需要脱敏的请求头
+	RedactPaths   []string          // 需要对响应数据脱敏的接口路径前缀
+	Transport     http.RoundTripper // 录制时发起真实请求使用的 RoundTripper，默认为 http.DefaultTransport
+
+	file     string
+	mode     Mode
+	lock     sync.Mutex
+	cassette *Cassette
+	used     []bool
+}
+
+// New 创建录制器，回放模式下读取录制文件
+func New(file string, mode Mode) (*Recorder, error) {
+	r := &Recorder{
+		RedactHeaders: DefaultRedactHeaders,
+		RedactPaths:   DefaultRedactPaths,
+		file:          file,
+		mode:          mode,
+		cassette:      new(Cassette),
+	}
+	if r.mode == ModeAuto {
+		r.mode = ModeRecord
+		if _, err := os.Stat(file); err == nil {
+			r.mode = ModeReplay
+		}
+	}
+	if r.mode != ModeReplay {
+		return r, nil
+	}
+
+	data, err := ioutil.ReadFile(file)
+	if err != nil {
+		return nil, err
+	}
+	if err = json.Unmarshal(data, r.cassette); err != nil {
+		return nil, fmt.Errorf("parse cassette %s failed: %w", file, err)
+	}
+	r.used = make([]bool, len(r.cassette.Interactions))
+	return r, nil
+}
+
+// Use 在测试期间让 SDK 的请求经过录制器，测试结束后恢复并保存录制文件
+func Use(tb testing.TB, file string, mode Mode) *Recorder {
+	tb.Helper()
+	r, err := New(file, mode)
+	if err != nil {
+		tb.Fatalf("load cassette failed: %s", err.Error())
+	}
+	old := api.SetHTTPTransport(r)
+	tb.Cleanup(func() {
+		api.SetHTTPTransport(old)
+		if err := r.Save(); err != nil {
+			tb.Errorf("save cassette failed: %s", err.Error())
+		}
+	})
+	return r
+}
+
+// Recording 是否处于录制模式
+func (r *Recorder) Recording() bool {
+	return r.mode == ModeRecord
+}
+
+// RoundTrip 录制模式下发起真实请求并记录，回放模式下按请求方法、路径与参数依次匹配已录制的响应
+func (r *Recorder) RoundTrip(req *http.Request) (*http.Response, error) {
+	if r.mode == ModeRecord {
+		return r.record(req)
+	}
+	return r.replay(req)
+}
+
+// Save 录制模式下将录制内容写入文件
+func (r *Recorder) Save() error {
+	if r.mode != ModeRecord {
+		return nil
+	}
+	r.lock.Lock()
+	data, err := json.MarshalIndent(r.cassette, "", "  ")
+	r.lock.Unlock()
+	if err != nil {
+		return err
+	}
+	if err = os.MkdirAll(filepath.Dir(r.file), 0755); err != nil {
+		return err
+	}
+	return ioutil.WriteFile(r.file, data, 0644)
+}
+
+func (r *Recorder) record(req *http.Request) (*http.Response, error) {
+	var reqBody []byte
+	if req.Body != nil {
+		var err error
+		if reqBody, err = ioutil.ReadAll(req.Body); err != nil {
+			return nil, err
+		}
+		req.Body.Close()
+		req.Body = ioutil.NopCloser(bytes.NewReader(reqBody))
+	}
+
+	transport := r.Transport
+	if transport == nil {
+		transport = http.DefaultTransport
+	}
+	resp, err := transport.RoundTrip(req)
+	if err != nil {
+		return nil, err
+	}
+	respBody, err := ioutil.ReadAll(resp.Body)
+	resp.Body.Close()
+	if err != nil {
+		return nil, err
+	}
+	resp.Body = ioutil.NopCloser(bytes.NewReader(respBody))
+
+	interaction := &Interaction{
+		Request: &Request{
+			Method:  req.Method,
+			Path:    req.URL.Path,
+			Query:   req.URL.Query().Encode(),
+			Headers: r.redactHeaders(req.Header),
+			Body:    string(reqBody),
+		},
+		Response: &Response{
+			Status:  resp.StatusCode,
+			Headers: responseHeaders(resp.Header),
+			Body:    r.redactBody(req.URL.Path, respBody),
+		},
+	}
+	r.lock.Lock()
+	r.cassette.Interactions = append(r.cassette.Interactions, interaction)
+	r.lock.Unlock()
+	return resp, nil
+}
+
+func (r *Recorder) replay(req *http.Request) (*http.Response, error) {
+	query := req.URL.Query().Encode()
+	r.lock.Lock()
+	defer r.lock.Unlock()
+	for i, interaction := range r.cassette.Interactions {
+		recorded := interaction.Request
+		if r.used[i] || recorded.Method != req.Method || recorded.Path != req.URL.Path || recorded.Query != query {
+			continue
+		}
+		r.used[i] = true
+
+		resp := &http.Response{
+			Status:        fmt.Sprintf("%d %s", interaction.Response.Status, http.StatusText(interaction.Response.Status)),
+			StatusCode:    interaction.Response.Status,
+			Proto:         "HTTP/1.1",
+			ProtoMajor:    1,
+			ProtoMinor:    1,
+			Header:        make(http.Header),
+			Body:          ioutil.NopCloser(strings.NewReader(interaction.Response.Body)),
+			ContentLength: int64(len(interaction.Response.Body)),
+			Request:       req,
+		}
+		for k, v := range interaction.Response.Headers {
+			resp.Header.Set(k, v)
+		}
+		return resp, nil
+	}
+	return nil, fmt.Errorf("cassette %s has no recorded interaction for %s %s?%s", r.file, req.Method, req.URL.Path, query)
+}
+
+func (r *Recorder) redactHeaders(header http.Header) map[string]string {
+	headers := flattenHeaders(header)
+	for _, name := range r.RedactHeaders {
+		key := http.CanonicalHeaderKey(name)
+		if _, ok := headers[key]; ok {
+			headers[key] = Redacted
+		}
+	}
+	return headers
+}
+
+// redactBody 将需要脱敏的接口返回的 data 中的值全部替换为 REDACTED
+func (r *Recorder) redactBody(path string, body []byte) string {
+	for _, prefix := range r.RedactPaths {
+		if !strings.HasPrefix(path, prefix) {
+			continue
+		}
+		var result map[string]interface{}
+		if err := json.Unmarshal(body, &result); err != nil {
+			return Redacted
+		}
+		if data, ok := result["data"]; ok {
+			result["data"] = redactValue(data)
+		}
+		redacted, _ := json.Marshal(result)
+		return string(redacted)
+	}
+	return string(body)
+}
+
+func redactValue(v interface{}) interface{} {
+	switch value := v.(type) {
+	case map[string]interface{}:
+		for k, item := range value {
+			value[k] = redactValue(item)
+		}
+		return value
+	case []interface{}:
+		for i, item := range value {
+			value[i] = redactValue(item)
+		}
+		return value
+	case nil:
+		return nil
+	default:
+		return Redacted
+	}
+}
+
+// responseHeaders 录制的响应头，去掉脱敏后会变化或每次请求都不同的内容
+func responseHeaders(header http.Header) map[string]string {
+	headers := flattenHeaders(header)
+	delete(headers, "Content-Length")
+	delete(headers, "Date")
+	return headers
+}
+
+func flattenHeaders(header http.Header) map[string]string {
+	headers := make(map[string]string, len(header))
+	for k, v := range header {
+		headers[k] = strings.Join(v, ", ")
+	}
+	return headers
+}
@@ -0,0 +1,135 @@
+package cassette
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/ci-plugins/golang-plugin-sdk/api"
+)
+
+func newServer(t *testing.T) *httptest.Server {
+	t.Helper()
+	calls := 0
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		calls++
+		w.Header().Set("Content-Type", "application/json")
+		switch {
+		case strings.HasPrefix(req.URL.Path, "/ticket/api/build/credentials/"):
+			fmt.Fprint(w, `{"status":0,"data":{"v1":"secret","list":["a",{"k":"b"}],"n":1,"none":null}}`)
+		default:
+			fmt.Fprintf(w, `{"status":0,"data":{"call":%d,"q":%q}}`, calls, req.URL.Query().Get("q"))
+		}
+	}))
+	t.Cleanup(srv.Close)
+	return srv
+}
+
+func get(t *testing.T, client *http.Client, url string) string {
+	t.Helper()
+	req, _ := http.NewRequest(http.MethodGet, url, nil)
+	req.Header.Set(api.AuthHeaderDevopsAgentSecretKey, "agent-secret")
+	req.Header.Set("X-Other", "kept")
+	resp, err := client.Do(req)
+	if err != nil {
+		t.Fatalf("GET %s error = %v", url, err)
+	}
+	defer resp.Body.Close()
+	body, _ := ioutil.ReadAll(resp.Body)
+	return string(body)
+}
+
+func TestRecordAndReplay(t *testing.T) {
+	srv := newServer(t)
+	file := filepath.Join(t.TempDir(), "testdata", "api.json")
+
+	rec, err := New(file, ModeRecord)
+	if err != nil {
+		t.Fatalf("New() error = %v", err)
+	}
+	client := &http.Client{Transport: rec}
+	first := get(t, client, srv.URL+"/process/api/build/variables?q=1")
+	second := get(t, client, srv.URL+"/process/api/build/variables?q=1")
+	credential := get(t, client, srv.URL+"/ticket/api/build/credentials/c1")
+	if !strings.Contains(credential, "secret") {
+		t.Errorf("recording should return the real response, got %s", credential)
+	}
+	if err = rec.Save(); err != nil {
+		t.Fatalf("Save() error = %v", err)
+	}
+
+	data, err := ioutil.ReadFile(file)
+	if err != nil {
+		t.Fatalf("read cassette: %v", err)
+	}
+	saved := string(data)
+	if strings.Contains(saved, "agent-secret") || strings.Contains(saved, `\"secret\"`) {
+		t.Errorf("cassette contains secrets:\n%s", saved)
+	}
+	var c Cassette
+	if err = json.Unmarshal(data, &c); err != nil || len(c.Interactions) != 3 {
+		t.Fatalf("cassette = %d interactions, %v", len(c.Interactions), err)
+	}
+	if got := c.Interactions[0].Request.Headers["X-Other"]; got != "kept" {
+		t.Errorf("X-Other header = %q, want kept", got)
+	}
+	if got := c.Interactions[2].Response.Body; got != `{"data":{"list":["REDACTED",{"k":"REDACTED"}],"n":"REDACTED","none":null,"v1":"REDACTED"},"status":0}` {
+		t.Errorf("redacted body = %s", got)
+	}
+	if _, ok := c.Interactions[0].Response.Headers["Content-Length"]; ok {
+		t.Errorf("Content-Length should not be recorded")
+	}
+
+	// 回放时服务已关闭，依次返回录制的响应
+	srv.Close()
+	replay, err := New(file, ModeReplay)
+	if err != nil {
+		t.Fatalf("New(replay) error = %v", err)
+	}
+	client = &http.Client{Transport: replay}
+	if got := get(t, client, srv.URL+"/process/api/build/variables?q=1"); got != first {
+		t.Errorf("first replay = %s, want %s", got, first)
+	}
+	if got := get(t, client, srv.URL+"/process/api/build/variables?q=1"); got != second {
+		t.Errorf("second replay = %s, want %s", got, second)
+	}
+	if _, err = client.Get(srv.URL + "/process/api/build/variables?q=1"); err == nil {
+		t.Errorf("replaying more requests than recorded should fail")
+	}
+	if _, err = client.Get(srv.URL + "/process/api/build/variables?q=2"); err == nil {
+		t.Errorf("replaying a request with a different query should fail")
+	}
+}
+
+func TestModeAuto(t *testing.T) {
+	file := filepath.Join(t.TempDir(), "auto.json")
+	rec, err := New(file, ModeAuto)
+	if err != nil || !rec.Recording() {
+		t.Fatalf("ModeAuto without file: recording = %v, err = %v", rec != nil && rec.Recording(), err)
+	}
+	if err = rec.Save(); err != nil {
+		t.Fatal(err)
+	}
+	rec, err = New(file, ModeAuto)
+	if err != nil || rec.Recording() {
+		t.Errorf("ModeAuto with file should replay, err = %v", err)
+	}
+	if _, err = New(filepath.Join(t.TempDir(), "missing.json"), ModeReplay); err == nil {
+		t.Errorf("ModeReplay without file error = nil")
+	}
+}
+
+func TestRedactBodyInvalidJSON(t *testing.T) {
+	rec := &Recorder{RedactPaths: DefaultRedactPaths}
+	if got := rec.redactBody("/repository/api/build/oauth/git", []byte("token=abc")); got != Redacted {
+		t.Errorf("redactBody(non json) = %q, want %q", got, Redacted)
+	}
+	if got := rec.redactBody("/process/api/build/variables", []byte("plain")); got != "plain" {
+		t.Errorf("redactBody(other path) = %q, want unchanged", got)
+	}
+}
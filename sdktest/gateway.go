@@ -0,0 +1,292 @@
+package sdktest
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/ci-plugins/golang-plugin-sdk/api"
+)
+
+// 网关接口路径
+const (
+	PathBuildVariable = "/process/api/build/variable/getBuildVariable"
+	PathBuildContext  = "/process/api/build/variable/get_build_context"
+	PathCredential    = "/ticket/api/build/credentials/"
+	PathCommits       = "/repository/api/build/commit/getCommitsByBuildId"
+	PathRepository    = "/repository/api/build/repositories/"
+	PathGitOauth      = "/repository/api/build/oauth/git/"
+)
+
+// RecordedRequest 网关收到的请求
+type RecordedRequest struct {
+	Method string
+	Path   string
+	Query  url.Values
+	Header http.Header
+	Body   []byte
+}
+
+// fault 注入的错误
+type fault struct {
+	status int
+	body   string
+}
+
+// Gateway 基于 httptest 的本地网关，实现插件使用的构建接口，
+// 可预置返回数据、校验鉴权请求头、记录收到的请求并注入错误与延迟
+type Gateway struct {
+	URL string
+
+	server *httptest.Server
+	lock   sync.Mutex
+
+	auth        *api.SdkEnv
+	buildVars   map[string]interface{}
+	contexts    map[string]string
+	credentials map[string]map[string]string
+	commits     []api.CommitResponse
+	repos       map[string]map[string]interface{}
+	gitOauth    map[string]map[string]interface{}
+	faults      map[string]*fault
+	latency     time.Duration
+	requests    []*RecordedRequest
+}
+
+// NewGateway 启动本地网关，测试结束后自动关闭
+func NewGateway(tb testing.TB) *Gateway {
+	tb.Helper()
+	g := &Gateway{
+		buildVars:   make(map[string]interface{}),
+		contexts:    make(map[string]string),
+		credentials: make(map[string]map[string]string),
+		repos:       make(map[string]map[string]interface{}),
+		gitOauth:    make(map[string]map[string]interface{}),
+		faults:      make(map[string]*fault),
+	}
+	g.server = httptest.NewServer(http.HandlerFunc(g.serveHTTP))
+	g.URL = g.server.URL
+	tb.Cleanup(g.server.Close)
+	return g
+}
+
+// ExpectAuth 校验请求携带的鉴权请求头与 env 一致，不一致时返回 401
+func (g *Gateway) ExpectAuth(env api.SdkEnv) *Gateway {
+	g.lock.Lock()
+	defer g.lock.Unlock()
+	g.auth = &env
+	return g
+}
+
+// SetBuildVar 预置构建变量
+func (g *Gateway) SetBuildVar(key string, value interface{}) *Gateway {
+	g.lock.Lock()
+	defer g.lock.Unlock()
+	g.buildVars[key] = value
+	return g
+}
+
+// SetContext 预置构建上下文
+func (g *Gateway) SetContext(name string, value string) *Gateway {
+	g.lock.Lock()
+	defer g.lock.Unlock()
+	g.contexts[name] = value
+	return g
+}
+
+// SetCredential 预置凭证
+func (g *Gateway) SetCredential(id string, data map[string]string) *Gateway {
+	g.lock.Lock()
+	defer g.lock.Unlock()
+	g.credentials[id] = data
+	return g
+}
+
+// AddCommits 预置代码变更记录
+func (g *Gateway) AddCommits(commits ...api.CommitResponse) *Gateway {
+	g.lock.Lock()
+	defer g.lock.Unlock()
+	g.commits = append(g.commits, commits...)
+	return g
+}
+
+// SetRepo 预置代码库信息，repoType 为 string(api.RepoTypeId) 或 string(api.RepoTypeName)
+func (g *Gateway) SetRepo(repoType string, repoId string, info map[string]interface{}) *Gateway {
+	g.lock.Lock()
+	defer g.lock.Unlock()
+	g.repos[repoType+"/"+repoId] = info
+	return g
+}
+
+// SetGitOauth 预置用户的 git oauth 信息
+func (g *Gateway) SetGitOauth(userId string, data map[string]interface{}) *Gateway {
+	g.lock.Lock()
+	defer g.lock.Unlock()
+	g.gitOauth[userId] = data
+	return g
+}
+
+// Fail 使路径以 pathPrefix 开头的请求返回指定的状态码与响应体，直到调用 ClearFaults
+func (g *Gateway) Fail(pathPrefix string, status int, body string) *Gateway {
+	g.lock.Lock()
+	defer g.lock.Unlock()
+	g.faults[pathPrefix] = &fault{status: status, body: body}
+	return g
+}
+
+// ClearFaults 清除注入的错误
+func (g *Gateway) ClearFaults() *Gateway {
+	g.lock.Lock()
+	defer g.lock.Unlock()
+	g.faults = make(map[string]*fault)
+	return g
+}
+
+// SetLatency 设置每个请求的响应延迟
+func (g *Gateway) SetLatency(d time.Duration) *Gateway {
+	g.lock.Lock()
+	defer g.lock.Unlock()
+	g.latency = d
+	return g
+}
+
+// Requests 获取收到的全部请求
+func (g *Gateway) Requests() []*RecordedRequest {
+	g.lock.Lock()
+	defer g.lock.Unlock()
+	requests := make([]*RecordedRequest, len(g.requests))
+	copy(requests, g.requests)
+	return requests
+}
+
+func (g *Gateway) serveHTTP(w http.ResponseWriter, req *http.Request) {
+	body, _ := ioutil.ReadAll(req.Body)
+	g.lock.Lock()
+	g.requests = append(g.requests, &RecordedRequest{
+		Method: req.Method,
+		Path:   req.URL.Path,
+		Query:  req.URL.Query(),
+		Header: req.Header.Clone(),
+		Body:   body,
+	})
+	latency := g.latency
+	f := g.matchFault(req.URL.Path)
+	authErr := g.checkAuth(req.Header)
+	g.lock.Unlock()
+
+	if latency > 0 {
+		time.Sleep(latency)
+	}
+	if f != nil {
+		w.WriteHeader(f.status)
+		w.Write([]byte(f.body))
+		return
+	}
+	if authErr != "" {
+		writeResult(w, http.StatusUnauthorized, authErr, nil)
+		return
+	}
+
+	g.lock.Lock()
+	defer g.lock.Unlock()
+	path := req.URL.Path
+	query := req.URL.Query()
+	switch {
+	case path == PathBuildVariable:
+		writeResult(w, http.StatusOK, "", g.buildVars)
+	case path == PathBuildContext:
+		value, ok := g.contexts[query.Get("contextName")]
+		if !ok {
+			writeResult(w, http.StatusOK, "", nil)
+			return
+		}
+		writeResult(w, http.StatusOK, "", value)
+	case strings.HasPrefix(path, PathCredential) && strings.HasSuffix(path, "/detail"):
+		id := strings.TrimSuffix(strings.TrimPrefix(path, PathCredential), "/detail")
+		data, ok := g.credentials[id]
+		if !ok {
+			writeResult(w, http.StatusNotFound, "credential "+id+" not found", nil)
+			return
+		}
+		writeResult(w, http.StatusOK, "", data)
+	case path == PathCommits:
+		commits := g.commits
+		if commits == nil {
+			commits = []api.CommitResponse{}
+		}
+		writeResult(w, http.StatusOK, "", commits)
+	case path == PathRepository:
+		key := query.Get("repositoryType") + "/" + query.Get("repositoryId")
+		info, ok := g.repos[key]
+		if !ok {
+			writeResult(w, http.StatusNotFound, "repository "+key+" not found", nil)
+			return
+		}
+		writeResult(w, http.StatusOK, "", info)
+	case strings.HasPrefix(path, PathGitOauth):
+		user := strings.TrimPrefix(path, PathGitOauth)
+		data, ok := g.gitOauth[user]
+		if !ok {
+			writeResult(w, http.StatusNotFound, "git oauth of "+user+" not found", nil)
+			return
+		}
+		writeResult(w, http.StatusOK, "", data)
+	default:
+		writeResult(w, http.StatusNotFound, "unknown path "+path, nil)
+	}
+}
+
+func (g *Gateway) matchFault(path string) *fault {
+	var matched *fault
+	var matchedLen int
+	for prefix, f := range g.faults {
+		if strings.HasPrefix(path, prefix) && len(prefix) >= matchedLen {
+			matched = f
+			matchedLen = len(prefix)
+		}
+	}
+	return matched
+}
+
+// checkAuth 校验鉴权请求头，返回不一致的描述
+func (g *Gateway) checkAuth(header http.Header) string {
+	if g.auth == nil {
+		return ""
+	}
+	expected := map[string]string{
+		api.AuthHeaderDevopsAgentId:        g.auth.AgentId,
+		api.AuthHeaderDevopsAgentSecretKey: g.auth.SecretKey,
+		api.AuthHeaderBuildId:              g.auth.BuildId,
+		api.AuthHeaderProjectId:            g.auth.ProjectId,
+		api.AuthHeaderDevopsVmSeqId:        g.auth.VmSeqId,
+		api.AuthHeaderDevopsCiTaskId:       g.auth.TaskId,
+		api.AuthHeaderDevopsBuildType:      g.auth.BuildType,
+	}
+	for key, value := range expected {
+		if header.Get(key) != value {
+			return "invalid auth header " + key
+		}
+	}
+	return ""
+}
+
+func writeResult(w http.ResponseWriter, status int, message string, data interface{}) {
+	result := map[string]interface{}{
+		"status": 0,
+		"data":   data,
+	}
+	if status != http.StatusOK {
+		result["status"] = status
+		result["message"] = message
+	}
+	body, _ := json.Marshal(result)
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	w.Write(body)
+}
@@ -0,0 +1,128 @@
+package sdktest
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/ci-plugins/golang-plugin-sdk/api"
+)
+
+// getJSON 直接请求网关并解析响应
+func getJSON(t *testing.T, url string) (int, map[string]interface{}) {
+	t.Helper()
+	resp, err := http.Get(url)
+	if err != nil {
+		t.Fatalf("GET %s error = %v", url, err)
+	}
+	defer resp.Body.Close()
+	body, _ := ioutil.ReadAll(resp.Body)
+	result := make(map[string]interface{})
+	if err = json.Unmarshal(body, &result); err != nil {
+		t.Fatalf("GET %s body %s: %v", url, body, err)
+	}
+	return resp.StatusCode, result
+}
+
+func TestGatewayServes(t *testing.T) {
+	g := NewGateway(t).
+		SetBuildVar("BK_CI_BUILD_NUM", "7").
+		SetContext("ci.branch", "master").
+		SetCredential("cred", map[string]string{"v1": "user", "v2": "pass"}).
+		SetGitOauth("tester", map[string]interface{}{"accessToken": "token"}).
+		SetRepo(string(api.RepoTypeId), "r1", map[string]interface{}{"url": "http://git/repo.git"}).
+		AddCommits(api.CommitResponse{Name: "repo", Records: []api.CommitData{{Commit: "abc"}}})
+	env := NewEnv(t).UseGateway(g)
+
+	env.Exec(func() {
+		if got, err := api.GetBuildVarByKey("BK_CI_BUILD_NUM"); err != nil || got != "7" {
+			t.Errorf("GetBuildVarByKey() = %q, %v", got, err)
+		}
+		if got := api.GetVariableByName("ci.branch"); got != "master" {
+			t.Errorf("GetVariableByName() = %q", got)
+		}
+		if got := api.GetCertificate("cred"); got["v1"] != "user" || got["v2"] != "pass" {
+			t.Errorf("GetCertificate() = %v", got)
+		}
+		if got, err := api.GetGitOauth("tester"); err != nil || got["accessToken"] != "token" {
+			t.Errorf("GetGitOauth() = %v, %v", got, err)
+		}
+		if got, err := api.GetRepoInfo(api.RepoTypeId, "r1"); err != nil || got["url"] != "http://git/repo.git" {
+			t.Errorf("GetRepoInfo() = %v, %v", got, err)
+		}
+		if got, err := api.GetCommit(); err != nil || len(got.Data) != 1 || got.Data[0].Records[0].Commit != "abc" {
+			t.Errorf("GetCommit() = %+v, %v", got, err)
+		}
+		if got := api.GetCertificate("missing"); got != nil {
+			t.Errorf("GetCertificate(missing) = %v, want nil", got)
+		}
+	})
+
+	requests := g.Requests()
+	if len(requests) != 7 {
+		t.Fatalf("recorded %d requests, want 7", len(requests))
+	}
+	if r := requests[0]; r.Method != http.MethodGet || r.Path != PathBuildVariable ||
+		r.Header.Get(api.AuthHeaderDevopsAgentSecretKey) != DefaultSecretKey {
+		t.Errorf("first request = %s %s %v", r.Method, r.Path, r.Header)
+	}
+	if r := requests[1]; r.Path != PathBuildContext || r.Query.Get("contextName") != "ci.branch" {
+		t.Errorf("context request = %s %v", r.Path, r.Query)
+	}
+	if r := requests[2]; r.Path != PathCredential+"cred/detail" {
+		t.Errorf("credential request path = %s", r.Path)
+	}
+}
+
+func TestGatewayAuth(t *testing.T) {
+	g := NewGateway(t).SetBuildVar("k", "v")
+	if status, _ := getJSON(t, g.URL+PathBuildVariable); status != http.StatusOK {
+		t.Errorf("request without ExpectAuth status = %d, want 200", status)
+	}
+
+	g.ExpectAuth(api.SdkEnv{AgentId: "agent", SecretKey: "secret"})
+	status, result := getJSON(t, g.URL+PathBuildVariable)
+	if status != http.StatusUnauthorized || result["message"] == nil {
+		t.Errorf("request without auth headers = %d %v, want 401", status, result)
+	}
+}
+
+func TestGatewayNoRoute(t *testing.T) {
+	g := NewGateway(t)
+	status, result := getJSON(t, g.URL+"/unknown/api")
+	if status != http.StatusNotFound || result["status"] != float64(http.StatusNotFound) || result["message"] != "unknown path /unknown/api" {
+		t.Errorf("unknown path = %d %v", status, result)
+	}
+	if status, result = getJSON(t, g.URL+PathGitOauth+"nobody"); status != http.StatusNotFound || result["data"] != nil {
+		t.Errorf("missing git oauth = %d %v", status, result)
+	}
+	if status, result = getJSON(t, g.URL+PathCommits); status != http.StatusOK || result["data"] == nil {
+		t.Errorf("commits without data = %d %v, want an empty list", status, result)
+	}
+	if len(g.Requests()) != 3 {
+		t.Errorf("unmatched requests should be recorded, got %d", len(g.Requests()))
+	}
+}
+
+func TestGatewayFaults(t *testing.T) {
+	g := NewGateway(t).SetBuildVar("k", "v")
+	g.Fail("/process/", http.StatusBadGateway, `{"status":502}`).
+		Fail(PathBuildVariable, http.StatusServiceUnavailable, `{"status":503}`)
+	if status, _ := getJSON(t, g.URL+PathBuildVariable); status != http.StatusServiceUnavailable {
+		t.Errorf("status = %d, want the longest matching fault 503", status)
+	}
+	if status, _ := getJSON(t, g.URL+PathBuildContext); status != http.StatusBadGateway {
+		t.Errorf("status = %d, want 502", status)
+	}
+
+	g.ClearFaults().SetLatency(20 * time.Millisecond)
+	start := time.Now()
+	if status, _ := getJSON(t, g.URL+PathBuildVariable); status != http.StatusOK {
+		t.Errorf("status after ClearFaults = %d, want 200", status)
+	}
+	if elapsed := time.Since(start); elapsed < 20*time.Millisecond {
+		t.Errorf("request took %s, want at least the latency", elapsed)
+	}
+}
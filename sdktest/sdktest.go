@@ -0,0 +1,271 @@
+/*
+Package sdktest 提供插件单元测试所需的运行环境
+
+NewEnv 会创建临时数据目录并生成 .sdk.json 与 input.json，
+Run 与 Exec 执行插件逻辑后返回解析后的 output.json 以及插件打印的 ##[...] 日志
+
+	func TestPlugin(t *testing.T) {
+		env := sdktest.NewEnv(t).SetInput("name", "demo")
+		res := env.Run(run)
+		if res.Status != api.StatusSuccess {
+			t.Fatal(res.Message)
+		}
+	}
+*/
+package sdktest
+
+import (
+	"bytes"
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/ci-plugins/golang-plugin-sdk/api"
+	"github.com/ci-plugins/golang-plugin-sdk/log"
+	"github.com/ci-plugins/golang-plugin-sdk/task"
+)
+
+// 测试环境默认的构建信息
+const (
+	DefaultProjectId  = "test-project"
+	DefaultPipelineId = "p-test"
+	DefaultBuildId    = "b-test"
+	DefaultTaskId     = "e-test"
+	DefaultAgentId    = "test-agent"
+	DefaultSecretKey  = "test-secret"
+)
+
+// Env 插件测试环境
+type Env struct {
+	tb testing.TB
+
+	Dir        string                 // 数据目录
+	SdkEnv     api.SdkEnv             // 写入 .sdk.json 的内容
+	Input      map[string]interface{} // 写入 input.json 的内容，默认包含 BK_CI_* 基础参数
+	PostAction string                 // -postAction 参数，为空时执行主流程
+	Task       *task.Task             // 插件的 task.json 配置，设置后会校验输入
+
+	OutputCheck api.OutputCheckMode // 写入输出前按 Task 校验输出的方式
+
+	gateway *Gateway
+}
+
+// NewEnv 创建测试环境，数据目录在测试结束后自动删除
+func NewEnv(tb testing.TB) *Env {
+	tb.Helper()
+	dir := tb.TempDir()
+	workspace := filepath.Join(dir, "workspace")
+	if err := os.MkdirAll(workspace, 0755); err != nil {
+		tb.Fatalf("create workspace failed: %s", err.Error())
+	}
+
+	return &Env{
+		tb:  tb,
+		Dir: dir,
+		SdkEnv: api.SdkEnv{
+			BuildType: api.BuildTypeWorker,
+			ProjectId: DefaultProjectId,
+			AgentId:   DefaultAgentId,
+			SecretKey: DefaultSecretKey,
+			BuildId:   DefaultBuildId,
+			VmSeqId:   "1",
+			TaskId:    DefaultTaskId,
+		},
+		Input: map[string]interface{}{
+			"BK_CI_PROJECT_NAME":     DefaultProjectId,
+			"BK_CI_PROJECT_NAME_CN":  DefaultProjectId,
+			"BK_CI_PIPELINE_ID":      DefaultPipelineId,
+			"BK_CI_PIPELINE_NAME":    "test pipeline",
+			"BK_CI_PIPELINE_VERSION": "1",
+			"BK_CI_BUILD_ID":         DefaultBuildId,
+			"BK_CI_BUILD_NUM":        "1",
+			"BK_CI_BUILD_TASK_ID":    DefaultTaskId,
+			"BK_CI_START_TYPE":       "MANUAL",
+			"BK_CI_START_USER_ID":    "tester",
+			"BK_CI_START_USER_NAME":  "tester",
+			"bkWorkspace":            workspace,
+		},
+	}
+}
+
+// SetInput 设置输入参数
+func (e *Env) SetInput(name string, value interface{}) *Env {
+	e.Input[name] = value
+	return e
+}
+
+// UseGateway 使插件的远程调用发往本地网关，并由网关校验鉴权请求头
+func (e *Env) UseGateway(g *Gateway) *Env {
+	e.gateway = g
+	return e
+}
+
+// Workspace 获取工作目录
+func (e *Env) Workspace() string {
+	ws, _ := e.Input["bkWorkspace"].(string)
+	return ws
+}
+
+// Run 使用 api.Run 执行插件逻辑
+func (e *Env) Run(fn api.HandlerFunc) *Result {
+	e.tb.Helper()
+	return e.exec(func(r *api.Runtime) {
+		r.Run(fn)
+	})
+}
+
+// Exec 执行 fn，fn 中对 api 包级函数的调用均使用测试环境的运行时，
+// 适用于调用 api.Start 或自行调用 FinishBuild 的插件入口
+// 注意 FinishBuild 等函数在测试环境中不会退出进程，之后的代码会继续执行
+func (e *Env) Exec(fn func()) *Result {
+	e.tb.Helper()
+	return e.exec(func(r *api.Runtime) {
+		fn()
+	})
+}
+
+func (e *Env) exec(fn func(r *api.Runtime)) *Result {
+	e.tb.Helper()
+	if e.gateway != nil {
+		e.SdkEnv.Gateway = e.gateway.URL
+		e.gateway.ExpectAuth(e.SdkEnv)
+	}
+	e.writeJSON(".sdk.json", e.SdkEnv)
+	e.writeJSON("input.json", e.Input)
+	outputFile := filepath.Join(e.Dir, "output.json")
+	os.Remove(outputFile)
+
+	e.tb.Setenv(api.DataDirEnv, e.Dir)
+	e.tb.Setenv(api.InputFileEnv, "input.json")
+	e.tb.Setenv(api.OutputFileEnv, "output.json")
+	// 测试结果不受开发机上运行时相关环境变量的影响，使用 Task 与 OutputCheck 设置
+	e.tb.Setenv(api.LocalEnv, "")
+	e.tb.Setenv(api.TaskFileEnv, "")
+	e.tb.Setenv(api.OutputCheckEnv, "")
+
+	exiter := new(api.CaptureExiter)
+	r, err := api.NewRuntime(&api.RuntimeOptions{PostAction: e.PostAction, OutputCheck: e.OutputCheck, Exiter: exiter})
+	if err != nil {
+		e.tb.Fatalf("create runtime failed: %s", err.Error())
+	}
+	if e.Task != nil {
+		r.SetTask(e.Task)
+	}
+
+	var logs bytes.Buffer
+	oldOutput := log.SetOutput(&logs)
+	api.SetDefaultRuntime(r)
+	func() {
+		defer func() {
+			api.SetDefaultRuntime(nil)
+			log.SetOutput(oldOutput)
+		}()
+		fn(r)
+	}()
+
+	res := &Result{
+		Exited:   exiter.Exited(),
+		ExitCode: exiter.Code(),
+		Logs:     parseLogs(logs.String()),
+	}
+	data, err := ioutil.ReadFile(outputFile)
+	if os.IsNotExist(err) {
+		return res
+	}
+	if err != nil {
+		e.tb.Fatalf("read output failed: %s", err.Error())
+	}
+	res.Output = new(api.AtomOutput)
+	if err = json.Unmarshal(data, res.Output); err != nil {
+		e.tb.Fatalf("parse output failed: %s", err.Error())
+	}
+	res.Status = res.Output.Status
+	res.Message = res.Output.Message
+	res.ErrorCode = res.Output.ErrorCode
+	res.ErrorType = res.Output.ErrorType
+	return res
+}
+
+func (e *Env) writeJSON(name string, v interface{}) {
+	e.tb.Helper()
+	data, err := json.Marshal(v)
+	if err != nil {
+		e.tb.Fatalf("marshal %s failed: %s", name, err.Error())
+	}
+	if err = ioutil.WriteFile(filepath.Join(e.Dir, name), data, 0644); err != nil {
+		e.tb.Fatalf("write %s failed: %s", name, err.Error())
+	}
+}
+
+// Result 插件执行结果
+type Result struct {
+	Status    api.Status
+	Message   string
+	ErrorCode int
+	ErrorType api.ErrorType
+	Exited    bool            // 是否已结束构建
+	ExitCode  int             // 结束构建时的退出码
+	Output    *api.AtomOutput // 解析后的 output.json，未写入时为 nil，Data 中的值为通用 JSON 结构
+	Logs      []string        // 插件打印的 ##[...] 日志
+}
+
+// Data 获取输出参数
+func (r *Result) Data(key string) interface{} {
+	if r.Output == nil {
+		return nil
+	}
+	return r.Output.Data[key]
+}
+
+// StringData 获取变量输出的值
+func (r *Result) StringData(key string) string {
+	data, _ := r.Data(key).(map[string]interface{})
+	value, _ := data["value"].(string)
+	return value
+}
+
+// ArtifactData 获取构件输出的文件列表
+func (r *Result) ArtifactData(key string) []string {
+	data, _ := r.Data(key).(map[string]interface{})
+	values, _ := data["value"].([]interface{})
+	artifacts := make([]string, 0, len(values))
+	for _, v := range values {
+		if s, ok := v.(string); ok {
+			artifacts = append(artifacts, s)
+		}
+	}
+	return artifacts
+}
+
+// QualityData 获取质量红线数据
+func (r *Result) QualityData(key string) string {
+	if r.Output == nil || r.Output.QualityData[key] == nil {
+		return ""
+	}
+	return r.Output.QualityData[key].Value
+}
+
+// LogsOf 获取指定级别的日志内容，level 如 info、warning、error
+func (r *Result) LogsOf(level string) []string {
+	prefix := "##[" + level + "]"
+	var logs []string
+	for _, l := range r.Logs {
+		if strings.HasPrefix(l, prefix) {
+			logs = append(logs, strings.TrimPrefix(l, prefix))
+		}
+	}
+	return logs
+}
+
+func parseLogs(s string) []string {
+	var logs []string
+	for _, l := range strings.Split(s, "\n") {
+		if strings.HasPrefix(l, "##[") {
+			logs = append(logs, l)
+		}
+	}
+	return logs
+}
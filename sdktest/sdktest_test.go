@@ -0,0 +1,130 @@
+package sdktest
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"github.com/ci-plugins/golang-plugin-sdk/api"
+	"github.com/ci-plugins/golang-plugin-sdk/log"
+	"github.com/ci-plugins/golang-plugin-sdk/task"
+)
+
+func readJSON(t *testing.T, file string, v interface{}) {
+	t.Helper()
+	data, err := ioutil.ReadFile(file)
+	if err != nil {
+		t.Fatalf("read %s: %v", file, err)
+	}
+	if err = json.Unmarshal(data, v); err != nil {
+		t.Fatalf("parse %s: %v", file, err)
+	}
+}
+
+func TestEnvFiles(t *testing.T) {
+	before := os.Getenv(api.DataDirEnv)
+	var dir string
+	t.Run("exec", func(t *testing.T) {
+		env := NewEnv(t).SetInput("name", "demo")
+		dir = env.Dir
+		res := env.Exec(func() {
+			if got := os.Getenv(api.DataDirEnv); got != env.Dir {
+				t.Errorf("%s = %q, want %q", api.DataDirEnv, got, env.Dir)
+			}
+			sdkEnv := new(api.SdkEnv)
+			readJSON(t, filepath.Join(env.Dir, ".sdk.json"), sdkEnv)
+			if sdkEnv.TaskId != DefaultTaskId || sdkEnv.SecretKey != DefaultSecretKey || sdkEnv.BuildType != api.BuildTypeWorker {
+				t.Errorf(".sdk.json = %+v", sdkEnv)
+			}
+			input := make(map[string]interface{})
+			readJSON(t, filepath.Join(env.Dir, "input.json"), &input)
+			if input["name"] != "demo" || input["BK_CI_BUILD_ID"] != DefaultBuildId {
+				t.Errorf("input.json = %v", input)
+			}
+
+			if api.GetInputParam("name") != "demo" || api.GetWorkspace() != env.Workspace() {
+				t.Errorf("runtime input name = %q, workspace = %q", api.GetInputParam("name"), api.GetWorkspace())
+			}
+			log.Warn("careful")
+			api.SetStringOutput("ver", "1")
+			api.AddArtifactOutput("pkg", "a.zip")
+			api.AddQualityData("q", api.NewQualityData("9"))
+			api.FinishBuild(api.StatusSuccess, "ok")
+		})
+
+		if !res.Exited || res.ExitCode != 0 || res.Status != api.StatusSuccess || res.Message != "ok" {
+			t.Errorf("result = %+v", res)
+		}
+		if res.StringData("ver") != "1" || !reflect.DeepEqual(res.ArtifactData("pkg"), []string{"a.zip"}) || res.QualityData("q") != "9" {
+			t.Errorf("output = %+v", res.Output)
+		}
+		if got := res.LogsOf("warning"); !reflect.DeepEqual(got, []string{"careful"}) {
+			t.Errorf("LogsOf(warning) = %q", got)
+		}
+	})
+
+	if got := os.Getenv(api.DataDirEnv); got != before {
+		t.Errorf("%s = %q after the test, want it restored to %q", api.DataDirEnv, got, before)
+	}
+	if _, err := os.Stat(dir); !os.IsNotExist(err) {
+		t.Errorf("data dir %s should be removed after the test", dir)
+	}
+}
+
+func TestEnvRun(t *testing.T) {
+	env := NewEnv(t)
+	res := env.Run(func(ctx *api.Context) error {
+		return api.UserErr(api.ErrorCodeInputInvalid, "bad input")
+	})
+	if !res.Exited || res.Status != api.StatusFailure || res.ErrorCode != api.ErrorCodeInputInvalid {
+		t.Errorf("result = %+v", res)
+	}
+
+	// 未结束构建时没有输出文件
+	res = env.Exec(func() {})
+	if res.Exited || res.Output != nil || res.Data("any") != nil || res.StringData("any") != "" {
+		t.Errorf("result without finish = %+v", res)
+	}
+}
+
+func TestEnvPostAction(t *testing.T) {
+	env := NewEnv(t)
+	env.PostAction = "cleanup"
+	env.Exec(func() {
+		if got := api.GetPostActionParam(); got != "cleanup" {
+			t.Errorf("GetPostActionParam() = %q, want cleanup", got)
+		}
+	})
+}
+
+func TestEnvOutputCheck(t *testing.T) {
+	tk, err := task.Parse([]byte(`{"atomCode": "demo", "output": {"ver": {"type": "string"}}}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	// 开发机上的环境变量不影响测试结果
+	t.Setenv(api.OutputCheckEnv, "strict")
+	t.Setenv(api.LocalEnv, "true")
+
+	run := func(mode api.OutputCheckMode) *Result {
+		env := NewEnv(t)
+		env.Task = tk
+		env.OutputCheck = mode
+		return env.Exec(func() {
+			if api.IsLocal() {
+				t.Errorf("IsLocal() = true, want the environment variable ignored")
+			}
+			api.SetStringOutput("extra", "1")
+			api.FinishBuild(api.StatusSuccess, "ok")
+		})
+	}
+	if res := run(api.OutputCheckOff); res.Status != api.StatusSuccess {
+		t.Errorf("OutputCheckOff result = %+v", res)
+	}
+	if res := run(api.OutputCheckStrict); res.Status != api.StatusError || res.ErrorCode != api.ErrorCodeOutputInvalid {
+		t.Errorf("OutputCheckStrict result = %+v", res)
+	}
+}
@@ -0,0 +1,420 @@
+/*
+Package task 解析插件的 task.json 配置
+
+task.json 中 input 与 output 的声明顺序决定了前端展示顺序，
+因此这里的 Inputs 与 Outputs 保留声明顺序，同名的重复声明也会保留以便校验
+*/
+package task
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+)
+
+// 输入组件类型
+const (
+	TypeInput                  = "vuex-input"
+	TypeTextarea               = "vuex-textarea"
+	TypeAceEditor              = "atom-ace-editor"
+	TypeSelector               = "selector"
+	TypeSelectInput            = "select-input"
+	TypeDevopsSelect           = "devops-select"
+	TypeEnumInput              = "enum-input"
+	TypeCheckbox               = "atom-checkbox"
+	TypeCheckboxList           = "atom-checkbox-list"
+	TypeTimePicker             = "time-picker"
+	TypeCronTimer              = "cron-timer"
+	TypeUserInput              = "user-input"
+	TypeCompanyStaffInput      = "company-staff-input"
+	TypeTips                   = "tips"
+	TypeParameter              = "parameter"
+	TypeDynamicParameter       = "dynamic-parameter"
+	TypeDynamicParameterSimple = "dynamic-parameter-simple"
+	TypeKeyValueNormal         = "key-value-normal"
+)
+
+var knownTypes = map[string]bool{
+	TypeInput:                  true,
+	TypeTextarea:               true,
+	TypeAceEditor:              true,
+	TypeSelector:               true,
+	TypeSelectInput:            true,
+	TypeDevopsSelect:           true,
+	TypeEnumInput:              true,
+	TypeCheckbox:               true,
+	TypeCheckboxList:           true,
+	TypeTimePicker:             true,
+	TypeCronTimer:              true,
+	TypeUserInput:              true,
+	TypeCompanyStaffInput:      true,
+	TypeTips:                   true,
+	TypeParameter:              true,
+	TypeDynamicParameter:       true,
+	TypeDynamicParameterSimple: true,
+	TypeKeyValueNormal:         true,
+}
+
+// IsKnownType 是否为已知的输入组件类型
+func IsKnownType(t string) bool {
+	return knownTypes[t]
+}
+
+// 输出类型
+const (
+	OutputTypeString   = "string"
+	OutputTypeArtifact = "artifact"
+	OutputTypeReport   = "report"
+)
+
+// Task 插件配置
+type Task struct {
+	AtomCode  string     `json:"atomCode"`
+	Execution *Execution `json:"execution,omitempty"`
+	Input     Inputs     `json:"input,omitempty"`
+	Output    Outputs    `json:"output,omitempty"`
+}
+
+// Execution 插件执行配置
+type Execution struct {
+	Language    string      `json:"language"`
+	PackagePath string      `json:"packagePath,omitempty"`
+	Demands     []string    `json:"demands,omitempty"`
+	Target      string      `json:"target,omitempty"`
+	Os          []*OsTarget `json:"os,omitempty"`
+	Post        *Post       `json:"post,omitempty"`
+}
+
+// GetPost 获取后置动作配置，未配置时返回 nil
+func (e *Execution) GetPost() *Post {
+	if e == nil {
+		return nil
+	}
+	return e.Post
+}
+
+// OsTarget 按操作系统区分的执行入口
+type OsTarget struct {
+	OsName      string   `json:"osName"`
+	OsArch      string   `json:"osArch,omitempty"`
+	Target      string   `json:"target"`
+	Demands     []string `json:"demands,omitempty"`
+	DefaultFlag bool     `json:"defaultFlag,omitempty"`
+}
+
+// Post 后置动作配置
+type Post struct {
+	PostEntryParam string `json:"postEntryParam"`
+	PostCondition  string `json:"postCondition,omitempty"`
+}
+
+// Input 输入字段声明
+type Input struct {
+	Name        string      `json:"-"`
+	Label       string      `json:"label,omitempty"`
+	Type        string      `json:"type"`
+	Default     interface{} `json:"default,omitempty"`
+	Placeholder string      `json:"placeholder,omitempty"`
+	Desc        string      `json:"desc,omitempty"`
+	Required    bool        `json:"required,omitempty"`
+	Disabled    bool        `json:"disabled,omitempty"`
+	Hidden      bool        `json:"hidden,omitempty"`
+	IsSensitive bool        `json:"isSensitive,omitempty"`
+	MultiSelect bool        `json:"multiSelect,omitempty"`
+	Options     []*Option   `json:"options,omitempty"`
+	List        []*Option   `json:"list,omitempty"`
+	Rule        *Rule       `json:"rule,omitempty"`
+	Rely        *Rely       `json:"rely,omitempty"`
+}
+
+// Choices 获取可选项，selector 类使用 options，enum-input 与 atom-checkbox-list 使用 list
+func (i *Input) Choices() []*Option {
+	if len(i.Options) > 0 {
+		return i.Options
+	}
+	return i.List
+}
+
+// Option 可选项，selector 与 atom-checkbox-list 使用 id/name，enum-input 使用 value/label
+type Option struct {
+	Id       string `json:"id,omitempty"`
+	Name     string `json:"name,omitempty"`
+	Value    string `json:"value,omitempty"`
+	Label    string `json:"label,omitempty"`
+	Desc     string `json:"desc,omitempty"`
+	Disabled bool   `json:"disabled,omitempty"`
+}
+
+// Key 可选项的取值
+func (o *Option) Key() string {
+	if o.Id != "" {
+		return o.Id
+	}
+	return o.Value
+}
+
+// Text 可选项的显示名称
+func (o *Option) Text() string {
+	if o.Name != "" {
+		return o.Name
+	}
+	return o.Label
+}
+
+// Rule 输入校验规则
+type Rule struct {
+	Regex     string `json:"regex,omitempty"`
+	Min       int    `json:"min,omitempty"`
+	Max       int    `json:"max,omitempty"`
+	Numeric   bool   `json:"numeric,omitempty"`
+	AlphaDash bool   `json:"alpha_dash,omitempty"`
+}
+
+// 显示条件的组合方式
+const (
+	RelyAnd = "AND"
+	RelyOr  = "OR"
+	RelyNot = "NOT"
+)
+
+// Rely 输入字段的显示条件
+type Rely struct {
+	Operation  string        `json:"operation"`
+	Expression []*Expression `json:"expression"`
+}
+
+// Expression 显示条件表达式，value 为数组时匹配其中任意一个
+type Expression struct {
+	Key   string      `json:"key"`
+	Value interface{} `json:"value"`
+}
+
+// Satisfied 根据当前输入判断显示条件是否满足
+func (r *Rely) Satisfied(values map[string]interface{}) bool {
+	if r == nil || len(r.Expression) == 0 {
+		return true
+	}
+	switch r.Operation {
+	case RelyOr:
+		for _, e := range r.Expression {
+			if e.match(values) {
+				return true
+			}
+		}
+		return false
+	case RelyNot:
+		for _, e := range r.Expression {
+			if e.match(values) {
+				return false
+			}
+		}
+		return true
+	default:
+		for _, e := range r.Expression {
+			if !e.match(values) {
+				return false
+			}
+		}
+		return true
+	}
+}
+
+func (e *Expression) match(values map[string]interface{}) bool {
+	actual := fmt.Sprint(values[e.Key])
+	if list, ok := e.Value.([]interface{}); ok {
+		for _, v := range list {
+			if fmt.Sprint(v) == actual {
+				return true
+			}
+		}
+		return false
+	}
+	return fmt.Sprint(e.Value) == actual
+}
+
+// Output 输出字段声明
+type Output struct {
+	Name        string `json:"-"`
+	Description string `json:"description,omitempty"`
+	Type        string `json:"type"`
+	IsSensitive bool   `json:"isSensitive,omitempty"`
+}
+
+// DataType 输出类型，未声明 type 时为 string
+func (o *Output) DataType() string {
+	if o.Type == "" {
+		return OutputTypeString
+	}
+	return o.Type
+}
+
+// Inputs 按声明顺序排列的输入字段
+type Inputs []*Input
+
+// Get 获取指定名称的输入字段，不存在时返回 nil
+func (in Inputs) Get(name string) *Input {
+	for _, i := range in {
+		if i.Name == name {
+			return i
+		}
+	}
+	return nil
+}
+
+// Duplicates 获取重复声明的输入字段名称
+func (in Inputs) Duplicates() []string {
+	names := make([]string, len(in))
+	for i, input := range in {
+		names[i] = input.Name
+	}
+	return duplicates(names)
+}
+
+// UnmarshalJSON 按声明顺序解析输入字段
+func (in *Inputs) UnmarshalJSON(data []byte) error {
+	*in = nil
+	return decodeObject(data, func(name string, dec *json.Decoder) error {
+		input := new(Input)
+		if err := dec.Decode(input); err != nil {
+			return fmt.Errorf("input %s: %w", name, err)
+		}
+		input.Name = name
+		*in = append(*in, input)
+		return nil
+	})
+}
+
+// MarshalJSON 按声明顺序输出输入字段
+func (in Inputs) MarshalJSON() ([]byte, error) {
+	return encodeObject(len(in), func(i int) (string, interface{}) {
+		return in[i].Name, in[i]
+	})
+}
+
+// Outputs 按声明顺序排列的输出字段
+type Outputs []*Output
+
+// Get 获取指定名称的输出字段，不存在时返回 nil
+func (out Outputs) Get(name string) *Output {
+	for _, o := range out {
+		if o.Name == name {
+			return o
+		}
+	}
+	return nil
+}
+
+// Duplicates 获取重复声明的输出字段名称
+func (out Outputs) Duplicates() []string {
+	names := make([]string, len(out))
+	for i, output := range out {
+		names[i] = output.Name
+	}
+	return duplicates(names)
+}
+
+// UnmarshalJSON 按声明顺序解析输出字段
+func (out *Outputs) UnmarshalJSON(data []byte) error {
+	*out = nil
+	return decodeObject(data, func(name string, dec *json.Decoder) error {
+		output := new(Output)
+		if err := dec.Decode(output); err != nil {
+			return fmt.Errorf("output %s: %w", name, err)
+		}
+		output.Name = name
+		*out = append(*out, output)
+		return nil
+	})
+}
+
+// MarshalJSON 按声明顺序输出输出字段
+func (out Outputs) MarshalJSON() ([]byte, error) {
+	return encodeObject(len(out), func(i int) (string, interface{}) {
+		return out[i].Name, out[i]
+	})
+}
+
+// Parse 解析 task.json 内容
+func Parse(data []byte) (*Task, error) {
+	t := new(Task)
+	if err := json.Unmarshal(data, t); err != nil {
+		return nil, err
+	}
+	return t, nil
+}
+
+// LoadFile 读取并解析 task.json 文件
+func LoadFile(path string) (*Task, error) {
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	t, err := Parse(data)
+	if err != nil {
+		return nil, fmt.Errorf("parse %s failed: %w", path, err)
+	}
+	return t, nil
+}
+
+func duplicates(names []string) []string {
+	var dup []string
+	seen := make(map[string]int)
+	for _, name := range names {
+		seen[name]++
+		if seen[name] == 2 {
+			dup = append(dup, name)
+		}
+	}
+	return dup
+}
+
+func decodeObject(data []byte, fn func(key string, dec *json.Decoder) error) error {
+	dec := json.NewDecoder(bytes.NewReader(data))
+	tok, err := dec.Token()
+	if err != nil {
+		return err
+	}
+	if tok == nil {
+		return nil
+	}
+	if delim, ok := tok.(json.Delim); !ok || delim != '{' {
+		return errors.New("expect json object")
+	}
+	for dec.More() {
+		tok, err = dec.Token()
+		if err != nil {
+			return err
+		}
+		if err = fn(tok.(string), dec); err != nil {
+			return err
+		}
+	}
+	_, err = dec.Token()
+	return err
+}
+
+func encodeObject(n int, item func(i int) (string, interface{})) ([]byte, error) {
+	var buf bytes.Buffer
+	buf.WriteByte('{')
+	for i := 0; i < n; i++ {
+		if i > 0 {
+			buf.WriteByte(',')
+		}
+		key, value := item(i)
+		k, err := json.Marshal(key)
+		if err != nil {
+			return nil, err
+		}
+		v, err := json.Marshal(value)
+		if err != nil {
+			return nil, err
+		}
+		buf.Write(k)
+		buf.WriteByte(':')
+		buf.Write(v)
+	}
+	buf.WriteByte('}')
+	return buf.Bytes(), nil
+}
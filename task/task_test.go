@@ -0,0 +1,142 @@
+package task
+
+import (
+	"encoding/json"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+const orderedTaskJSON = `{
+	"atomCode": "demo",
+	"input": {
+		"zeta": {"type": "vuex-input", "label": "Z"},
+		"alpha": {"type": "selector", "options": [{"id": "a", "name": "A"}]},
+		"mid": {"type": "enum-input", "list": [{"value": "x", "label": "X"}]},
+		"alpha": {"type": "vuex-input"}
+	},
+	"output": {
+		"out_b": {"type": "artifact"},
+		"out_a": {"description": "a"},
+		"out_b": {"type": "string"}
+	}
+}`
+
+func TestParseKeepsOrder(t *testing.T) {
+	tk, err := Parse([]byte(orderedTaskJSON))
+	if err != nil {
+		t.Fatalf("Parse() error = %v", err)
+	}
+
+	var inputs, outputs []string
+	for _, i := range tk.Input {
+		inputs = append(inputs, i.Name)
+	}
+	for _, o := range tk.Output {
+		outputs = append(outputs, o.Name)
+	}
+	if want := []string{"zeta", "alpha", "mid", "alpha"}; !reflect.DeepEqual(inputs, want) {
+		t.Errorf("inputs = %v, want %v", inputs, want)
+	}
+	if want := []string{"out_b", "out_a", "out_b"}; !reflect.DeepEqual(outputs, want) {
+		t.Errorf("outputs = %v, want %v", outputs, want)
+	}
+	if got := tk.Input.Duplicates(); !reflect.DeepEqual(got, []string{"alpha"}) {
+		t.Errorf("Input.Duplicates() = %v", got)
+	}
+	if got := tk.Output.Duplicates(); !reflect.DeepEqual(got, []string{"out_b"}) {
+		t.Errorf("Output.Duplicates() = %v", got)
+	}
+	if got := tk.Input.Get("alpha"); got == nil || got.Type != TypeSelector {
+		t.Errorf("Input.Get() should return the first declaration, got %+v", got)
+	}
+	if tk.Input.Get("missing") != nil || tk.Output.Get("missing") != nil {
+		t.Errorf("Get() of a missing name should return nil")
+	}
+	if got := tk.Input.Get("mid").Choices(); len(got) != 1 || got[0].Key() != "x" || got[0].Text() != "X" {
+		t.Errorf("Choices() of enum-input = %+v", got)
+	}
+}
+
+func TestMarshalRoundTrip(t *testing.T) {
+	tk, err := Parse([]byte(orderedTaskJSON))
+	if err != nil {
+		t.Fatalf("Parse() error = %v", err)
+	}
+	data, err := json.Marshal(tk)
+	if err != nil {
+		t.Fatalf("Marshal() error = %v", err)
+	}
+	s := string(data)
+	if strings.Index(s, `"zeta"`) > strings.Index(s, `"alpha"`) || strings.Index(s, `"out_b"`) > strings.Index(s, `"out_a"`) {
+		t.Errorf("Marshal() does not keep declaration order: %s", s)
+	}
+
+	again, err := Parse(data)
+	if err != nil {
+		t.Fatalf("Parse(Marshal()) error = %v", err)
+	}
+	if !reflect.DeepEqual(tk, again) {
+		t.Errorf("round trip changed task:\n%s", data)
+	}
+}
+
+func TestParseInvalid(t *testing.T) {
+	tests := map[string]string{
+		"syntax":      `{"input": {`,
+		"input array": `{"input": []}`,
+		"bad input":   `{"input": {"a": {"required": "yes"}}}`,
+		"bad output":  `{"output": {"a": 1}}`,
+	}
+	for name, data := range tests {
+		t.Run(name, func(t *testing.T) {
+			if _, err := Parse([]byte(data)); err == nil {
+				t.Errorf("Parse(%s) error = nil", data)
+			}
+		})
+	}
+
+	tk, err := Parse([]byte(`{"input": null}`))
+	if err != nil || len(tk.Input) != 0 {
+		t.Errorf("Parse(null input) = %v, %v", tk, err)
+	}
+}
+
+func TestRelySatisfied(t *testing.T) {
+	values := map[string]interface{}{"mode": "slow", "enabled": true, "count": float64(3)}
+	tests := []struct {
+		name string
+		rely *Rely
+		want bool
+	}{
+		{"nil", nil, true},
+		{"empty", &Rely{Operation: RelyAnd}, true},
+		{"and", &Rely{Operation: RelyAnd, Expression: []*Expression{{Key: "mode", Value: "slow"}, {Key: "enabled", Value: true}}}, true},
+		{"and one fails", &Rely{Operation: RelyAnd, Expression: []*Expression{{Key: "mode", Value: "slow"}, {Key: "enabled", Value: false}}}, false},
+		{"default is and", &Rely{Expression: []*Expression{{Key: "mode", Value: "fast"}}}, false},
+		{"or", &Rely{Operation: RelyOr, Expression: []*Expression{{Key: "mode", Value: "fast"}, {Key: "count", Value: float64(3)}}}, true},
+		{"or none", &Rely{Operation: RelyOr, Expression: []*Expression{{Key: "mode", Value: "fast"}}}, false},
+		{"not", &Rely{Operation: RelyNot, Expression: []*Expression{{Key: "mode", Value: "fast"}}}, true},
+		{"not matched", &Rely{Operation: RelyNot, Expression: []*Expression{{Key: "mode", Value: "slow"}}}, false},
+		{"value list", &Rely{Operation: RelyAnd, Expression: []*Expression{{Key: "mode", Value: []interface{}{"fast", "slow"}}}}, true},
+		{"missing key", &Rely{Operation: RelyAnd, Expression: []*Expression{{Key: "other", Value: "x"}}}, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.rely.Satisfied(values); got != tt.want {
+				t.Errorf("Satisfied() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestExecutionGetPost(t *testing.T) {
+	var e *Execution
+	if e.GetPost() != nil {
+		t.Errorf("nil Execution GetPost() should be nil")
+	}
+	e = &Execution{Post: &Post{PostEntryParam: "post"}}
+	if got := e.GetPost(); got == nil || got.PostEntryParam != "post" {
+		t.Errorf("GetPost() = %+v", got)
+	}
+}